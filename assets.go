@@ -0,0 +1,58 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// Everything the game loads at runtime, built into the binary so it runs
+// from any directory
+//
+//go:embed *.png *.glsl sprites.json
+var embeddedAssets embed.FS
+
+var gAssetDir = flag.String("assets", "", "directory of files that replace the built-in assets, for modding")
+
+// Virtual filesystem the game loads from. Files in dir win over the
+// embedded ones, anything it doesn't have falls through.
+type AssetFS struct {
+	dir  string
+	base fs.FS
+}
+
+var gAssets fs.FS = embeddedAssets
+
+func MakeAssetFS(dir string) fs.FS {
+	if dir == "" {
+		return embeddedAssets
+	}
+	return &AssetFS{dir, embeddedAssets}
+}
+
+func (a *AssetFS) Open(name string) (fs.File, error) {
+	f, err := os.DirFS(a.dir).Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return a.base.Open(name)
+}
+
+// Asset names are slash separated and relative, "./ball.png" is "ball.png"
+func assetPath(name string) string {
+	return path.Clean(name)
+}
+
+func readAsset(name string) ([]byte, error) {
+	return fs.ReadFile(gAssets, assetPath(name))
+}
+
+func openAsset(name string) (fs.File, error) {
+	return gAssets.Open(assetPath(name))
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"image"
+	"image/draw"
+	"sort"
+)
+
+// Border around every packed image, filled with copies of its edge pixels
+// so linear filtering at the edge of a region never picks up a neighbour
+const AtlasPadding = 1
+
+const AtlasMaxSize = 4096
+
+// One entry of sprites.json. Sheets are cut into a row-major grid of
+// FrameWidth x FrameHeight frames, plain sprites leave the frame fields
+// empty and are a single frame.
+type SpriteSheet struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	FrameWidth  int    `json:"frameWidth"`
+	FrameHeight int    `json:"frameHeight"`
+	Frames      int    `json:"frames"`
+}
+
+// Many sprites sharing one texture
+type Atlas struct {
+	tex    uint32
+	width  int
+	height int
+	//texture region per frame, in RenderComponent.texRegion form
+	frames map[string][]mgl.Vec4
+}
+
+var gAtlas *Atlas
+
+// Always in the atlas, drawn for sprites that aren't
+const MissingSprite = "missing"
+
+func (a *Atlas) Frames(name string) []mgl.Vec4 {
+	frames, ok := a.frames[name]
+	if !ok {
+		fmt.Printf("sprite %q is not in the atlas - using placeholder\n", name)
+		//only complain once
+		frames = a.frames[MissingSprite]
+		a.frames[name] = frames
+	}
+	return frames
+}
+
+// First frame of a sprite
+func (a *Atlas) Region(name string) mgl.Vec4 {
+	return a.Frames(name)[0]
+}
+
+type atlasEntry struct {
+	name  string
+	frame int
+	img   image.Image
+	//placement in the atlas, excluding padding
+	x int
+	y int
+}
+
+type AtlasBuilder struct {
+	entries []*atlasEntry
+}
+
+func MakeAtlasBuilder() *AtlasBuilder {
+	return &AtlasBuilder{}
+}
+
+// Single-frame sprite from an image already in memory
+func (b *AtlasBuilder) Add(name string, img image.Image) error {
+	return b.AddSheet(&SpriteSheet{Name: name}, img)
+}
+
+func (b *AtlasBuilder) AddSheet(sheet *SpriteSheet, img image.Image) error {
+	bounds := img.Bounds()
+	if sheet.Frames <= 1 || sheet.FrameWidth == 0 || sheet.FrameHeight == 0 {
+		b.entries = append(b.entries, &atlasEntry{name: sheet.Name, img: img})
+		return nil
+	}
+
+	sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return fmt.Errorf("sprite sheet %q: image can't be sliced", sheet.Name)
+	}
+	columns := bounds.Dx() / sheet.FrameWidth
+	if columns == 0 || (sheet.Frames+columns-1)/columns*sheet.FrameHeight > bounds.Dy() {
+		return fmt.Errorf("sprite sheet %q: %v image is too small for %d %dx%d frames",
+			sheet.Name, bounds.Size(), sheet.Frames, sheet.FrameWidth, sheet.FrameHeight)
+	}
+	for i := 0; i < sheet.Frames; i++ {
+		x := bounds.Min.X + (i%columns)*sheet.FrameWidth
+		y := bounds.Min.Y + (i/columns)*sheet.FrameHeight
+		rect := image.Rect(x, y, x+sheet.FrameWidth, y+sheet.FrameHeight)
+		b.entries = append(b.entries, &atlasEntry{name: sheet.Name, frame: i, img: sub.SubImage(rect)})
+	}
+	return nil
+}
+
+// Shelf packing: tallest images first, left to right in rows. Tries
+// growing power of two widths until everything fits under AtlasMaxSize.
+func (b *AtlasBuilder) pack() (width, height int, err error) {
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return b.entries[i].img.Bounds().Dy() > b.entries[j].img.Bounds().Dy()
+	})
+
+	for width = 64; width <= AtlasMaxSize; width *= 2 {
+		x, y, shelf := 0, 0, 0
+		fits := true
+		for _, e := range b.entries {
+			w := e.img.Bounds().Dx() + 2*AtlasPadding
+			h := e.img.Bounds().Dy() + 2*AtlasPadding
+			if w > width {
+				fits = false
+				break
+			}
+			if x+w > width {
+				x = 0
+				y += shelf
+				shelf = 0
+			}
+			e.x = x + AtlasPadding
+			e.y = y + AtlasPadding
+			x += w
+			if h > shelf {
+				shelf = h
+			}
+		}
+		height = nextPowerOfTwo(y + shelf)
+		if fits && height <= AtlasMaxSize {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("sprites don't fit in a %dx%d atlas", AtlasMaxSize, AtlasMaxSize)
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
+
+// Pack every added image into one RGBA image
+func (b *AtlasBuilder) Compose() (*image.RGBA, map[string][]mgl.Vec4, error) {
+	width, height, err := b.pack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	frames := make(map[string][]mgl.Vec4)
+	for _, e := range b.entries {
+		src := e.img.Bounds()
+		dst := image.Rect(e.x, e.y, e.x+src.Dx(), e.y+src.Dy())
+		draw.Draw(rgba, dst, e.img, src.Min, draw.Src)
+		extrude(rgba, dst)
+
+		region := mgl.Vec4{
+			float64(dst.Min.X) / float64(width),
+			float64(dst.Min.Y) / float64(height),
+			float64(dst.Dx()) / float64(width),
+			float64(dst.Dy()) / float64(height),
+		}
+		for len(frames[e.name]) <= e.frame {
+			frames[e.name] = append(frames[e.name], mgl.Vec4{})
+		}
+		frames[e.name][e.frame] = region
+	}
+	return rgba, frames, nil
+}
+
+// Copy the outermost pixels of r into the padding around it
+func extrude(img *image.RGBA, r image.Rectangle) {
+	for p := 1; p <= AtlasPadding; p++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, r.Min.Y-p, img.At(x, r.Min.Y))
+			img.Set(x, r.Max.Y-1+p, img.At(x, r.Max.Y-1))
+		}
+		for y := r.Min.Y - p; y < r.Max.Y+p; y++ {
+			img.Set(r.Min.X-p, y, img.At(r.Min.X, y))
+			img.Set(r.Max.X-1+p, y, img.At(r.Max.X-1, y))
+		}
+	}
+}
+
+func (b *AtlasBuilder) Build() (*Atlas, error) {
+	rgba, frames, err := b.Compose()
+	if err != nil {
+		return nil, err
+	}
+	tex, err := createTextureFromImage(rgba, gl.LINEAR)
+	if err != nil {
+		return nil, err
+	}
+	size := rgba.Bounds().Size()
+	return &Atlas{tex, size.X, size.Y, frames}, nil
+}
+
+// Build the shared atlas from a sprites.json style descriptor file, plus
+// the generated images the game needs. Images that fail to load become
+// placeholders, the descriptor itself has to be valid.
+func LoadAtlas(descriptorFile string) (*Atlas, error) {
+	descriptor, err := getFileAsString(descriptorFile)
+	if err != nil {
+		return nil, err
+	}
+	var sheets []*SpriteSheet
+	if err := json.Unmarshal([]byte(descriptor), &sheets); err != nil {
+		return nil, fmt.Errorf("%v: %w", descriptorFile, err)
+	}
+
+	builder := MakeAtlasBuilder()
+	for _, sheet := range sheets {
+		//a whole sheet's worth of placeholder keeps the frames sliceable
+		frames := sheet.Frames
+		if frames < 1 {
+			frames = 1
+		}
+		img := loadImageOrPlaceholder(sheet.File, sheet.FrameWidth*frames, sheet.FrameHeight)
+		if err := builder.AddSheet(sheet, img); err != nil {
+			return nil, err
+		}
+	}
+	white := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(white, white.Bounds(), image.White, image.Point{}, draw.Src)
+	generated := []struct {
+		name string
+		img  image.Image
+	}{
+		{"dot", makeDotImage(16)},
+		{"white", white},
+		{MissingSprite, makePlaceholderImage(16, 16)},
+	}
+	for _, g := range generated {
+		if err := builder.Add(g.name, g.img); err != nil {
+			return nil, err
+		}
+	}
+
+	atlas, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", descriptorFile, err)
+	}
+	return atlas, nil
+}
+
+// Frame for a normalised time t in [0, 1], e.g. a particle's age over
+// its lifetime
+func FrameAt(frames []mgl.Vec4, t float64) mgl.Vec4 {
+	i := int(t * float64(len(frames)))
+	if i >= len(frames) {
+		i = len(frames) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return frames[i]
+}
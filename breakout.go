@@ -13,11 +13,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
 	glfw "github.com/go-gl/glfw3/v3.2/glfw"
-	mgl32 "github.com/go-gl/mathgl/mgl32"
 	mgl "github.com/go-gl/mathgl/mgl64"
-	"math"
 	"runtime"
 	"time"
 )
@@ -26,85 +26,68 @@ const (
 	WindowWidth   = 600
 	WindowHeight  = 800
 	WindowTitle   = "Cylinoid"
-	TimePerUpdate = time.Duration(time.Second / 60.0)
+	TimePerUpdate = sim.TimePerUpdate
 )
 
-var gPause = false
-var gPaddle *Paddle = nil
-var gBall *Ball = nil
-var gBlocks []*Block
+var gFullscreen = flag.Bool("fullscreen", false, "start in fullscreen mode")
+
+var gWindow *glfw.Window
 var gCamPos mgl.Vec3
 var gLevelWidth float64
 
+// Key releases that happen while another window has focus never reach us,
+// so start from nothing held when focus goes
+func glfwFocusCallback(w *glfw.Window, focused bool) {
+	if !focused {
+		gKeys.Reset()
+		gMouse.Reset()
+	}
+}
+
 func glfwErrorCallback(err glfw.ErrorCode, desc string) {
 	fmt.Printf("%v: %v\n", err, desc)
 }
 
 func glfwKeyCallback(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	if gPaddle != nil &&
-		gPaddle.GetController()(gPaddle, key, scancode, action, mods) {
+	gKeys.Event(key, action, mods)
+
+	if action == glfw.Press && gInput.Is(ActionFullscreen, key, mods) {
+		ToggleFullscreen(w)
 		return
 	}
 
-	if action == glfw.Press && key == glfw.KeyP {
-		gPause = !gPause
+	if gStates.HandleKey(key, scancode, action, mods) {
+		return
 	}
 
 	if action == glfw.Press {
 		inc := float64(0.05)
-		switch key {
-		case glfw.KeyW:
+		switch {
+		case gInput.Is(ActionCameraUp, key, mods):
 			gCamPos[1] += inc
-		case glfw.KeyA:
+		case gInput.Is(ActionCameraLeft, key, mods):
 			gCamPos[0] -= inc
-		case glfw.KeyS:
+		case gInput.Is(ActionCameraDown, key, mods):
 			gCamPos[1] -= inc
-		case glfw.KeyD:
+		case gInput.Is(ActionCameraRight, key, mods):
 			gCamPos[0] += inc
-		case glfw.KeyQ:
+		case gInput.Is(ActionCameraOut, key, mods):
 			gCamPos[2] += inc
-		case glfw.KeyE:
+		case gInput.Is(ActionCameraIn, key, mods):
 			gCamPos[2] -= inc
 		}
 	}
 
 }
 
-func PopulateBlocks(sceneSize mgl.Vec2) {
-	// Number of blocks
-	horizBlocks := 10
-	vertBlocks := 4
-
-	// Padding around blockfield
-	var vertStartNorm float64 = .7 // .55
-	var horizStartNorm float64 = 0 // .1
-	var vertStart float64 = vertStartNorm * sceneSize[1]
-	var horizStart float64 = horizStartNorm * sceneSize[0]
-
-	// Amount of space taken up by whole blockfield
-	horizSpace := float64(1) //float64(.8)
-	vertSpace := float64(.3)
-
-	blockWidth := sceneSize[0] * horizSpace / float64(horizBlocks)
-	blockHeight := sceneSize[1] * vertSpace / float64(vertBlocks)
-	blockSize := mgl.Vec2{blockWidth, blockHeight}
-
-	gBlocks = make([]*Block, horizBlocks*vertBlocks)
-
-	color := mgl.Vec3{0, 1, 0}
-
-	for r := 0; r < vertBlocks; r++ {
-		posy := float64(r)*blockHeight + vertStart
-
-		for c := 0; c < horizBlocks; c++ {
-			posx := float64(c)*blockWidth + horizStart
-			gBlocks[r*horizBlocks+c] = MakeBlock(blockSize, mgl.Vec2{posx, posy}, color)
-		}
+func main() {
+	flag.Parse()
+	if err := LoadOptions(*gConfigFile); err != nil {
+		panic(err)
 	}
+	gOptions.Apply()
+	gAssets = MakeAssetFS(*gAssetDir)
 
-}
-
-func main() {
 	// lock glfw/gl calls to a single thread
 	runtime.LockOSThread()
 
@@ -116,146 +99,99 @@ func main() {
 	}
 	defer glfw.Terminate()
 
-	// Open glfw window, with GL4.1 context
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.Resizable, 0)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-
-	window, err := glfw.CreateWindow(WindowWidth, WindowHeight, WindowTitle, nil, nil)
+	// Open glfw window, with the newest GL context available
+	window, err := CreateWindow(WindowWidth, WindowHeight, WindowTitle)
 	if err != nil {
 		panic(err)
 	}
 	defer window.Destroy()
+	gWindow = window
 
 	window.SetKeyCallback(glfwKeyCallback)
+	window.SetCursorPosCallback(glfwCursorPosCallback)
+	window.SetMouseButtonCallback(glfwMouseButtonCallback)
+	window.SetFocusCallback(glfwFocusCallback)
 
 	window.MakeContextCurrent()
 	glfw.SwapInterval(1)
 
-	InitGL()
-
-	height := float64(2)
-	width := height * float64(WindowWidth) / float64(WindowHeight)
-	gLevelWidth = width
-	stageSize := mgl.Vec2{width, height}
+	if err := InitGL(); err != nil {
+		panic(err)
+	}
+	InitScreen(window)
+	if gAtlas, err = LoadAtlas("sprites.json"); err != nil {
+		panic(err)
+	}
+	if *gFullscreen {
+		ToggleFullscreen(window)
+	}
 
-	gPaddle = MakePaddle(0.4, stageSize)
-	gBall = MakeBall(0.05, mgl.Vec2{width / 2, height / 2})
-	PopulateBlocks(stageSize)
+	stageSize := sim.DefaultStageSize
+	gLevelWidth = stageSize[0]
 
 	gCamPos = mgl.Vec3{0, 5, 11}
-	persp := mgl32.Perspective(45, float32(width/height), 0.1, 100)
+	if gHUD, err = MakeHUD(); err != nil {
+		panic(err)
+	}
+	if gPost, err = MakePostChain(); err != nil {
+		//the game is still playable without effects
+		fmt.Println("post-processing disabled:", err)
+		gPost = &PostChain{}
+	}
+	title := MakeTitleState(stageSize)
+	gStates.Push(title)
+	if err := StartNetSession(); err != nil {
+		title.menu.ShowError("COULD NOT START NETWORK GAME", err)
+	} else if gNet != nil {
+		gStates.Push(MakeConnectState(stageSize))
+	} else if gServer != nil {
+		if play, err := MakeServerPlayingState(stageSize, gServer); err != nil {
+			QuitToTitle(stageSize).menu.ShowError("COULD NOT START GAME", err)
+		} else {
+			gStates.Push(play)
+		}
+	} else if *gEditFile != "" {
+		if editor, err := MakeEditorState(stageSize, *gEditFile); err != nil {
+			title.menu.ShowError("COULD NOT OPEN LEVEL", err)
+		} else {
+			gStates.Push(editor)
+		}
+	}
+	defer func() {
+		gNet.Close()
+		gServer.Close()
+	}()
 
 	//VP := mgl.Ortho(-width/2, width/2, 0, height*2, -4, 4)
 
 	previousTime := time.Now()
 
-	cameraPos := float64(stageSize[0] / 2)
-
 	var lag time.Duration
 	for !window.ShouldClose() {
 		glfw.PollEvents()
-
-		// Escape key press closes window
-		if window.GetKey(glfw.KeyEscape) == glfw.Press {
-			window.SetShouldClose(true)
-		}
+		gGamepad.Poll()
+		gNet.Poll()
+		gMouse.UpdateCapture(window)
 
 		currentTime := time.Now()
 		elapsed := currentTime.Sub(previousTime)
 		previousTime = currentTime
 
-		if gPause {
-			time.Sleep(200 * time.Millisecond)
-			continue
-		}
-
 		lag += elapsed
 
 		// Constant time-step updates
 		for lag >= TimePerUpdate {
-
-			gPaddle.Update(stageSize)
-			gBall.Update(stageSize)
-			//update blocks?
-
-			// Collision handling
-			var colliders []Collider
-			// ball is dynamic, others are static
-			colliders = append(colliders, gPaddle)
-			colliders = append(colliders, gBall)
-			for _, b := range gBlocks {
-				colliders = append(colliders, b)
-			}
-
-			CollideAll(colliders)
-
-			var killBlocks []int
-			for index, b := range gBlocks {
-				if !b.alive {
-					killBlocks = append(killBlocks, index)
-				}
-			}
-
-			for i := len(killBlocks) - 1; i >= 0; i-- {
-				idx := killBlocks[i]
-				gBlocks = append(gBlocks[:idx], gBlocks[idx+1:]...)
-			}
-			if len(gBlocks) == 0 {
-				PopulateBlocks(stageSize)
-			}
-
+			gStates.Update()
 			lag -= TimePerUpdate
 		}
 
-		// Render once per loop
+		// Render once per loop, through the post-processing chain
+		gPost.Begin()
 		ClearScreen()
 
-		// Camera logic
-		c := cameraPos
-		p := float64(gPaddle.pos[0] + gPaddle.size[0]/2)
-		dirLeft := true
-		dist := c - p
-		stageWidth := float64(stageSize[0])
-		maxDist := .2 * stageWidth
-		if math.Abs(dist) > stageWidth/2 {
-			dist = (stageWidth - math.Abs(dist)) * Sign(dist)
-			dirLeft = false
-		}
-		if math.Abs(dist) > maxDist {
-			moveDist := (math.Abs(dist) - maxDist) * Sign(dist)
-			if dirLeft {
-				cameraPos -= moveDist
-			} else {
-				cameraPos += moveDist
-			}
-			if cameraPos > stageWidth {
-				cameraPos -= stageWidth
-			} else if cameraPos < 0 {
-				cameraPos += stageWidth
-			}
-
-		}
-		//fmt.Println((cameraPos - p) / stageWidth)
-
-		//model := mgl.HomogRotate3DY(-gPaddle.pos[0] / gLevelWidth * 2 * math.Pi)
-		//"Model" transformation is the view angle, emulates camera
-		//model := mgl.HomogRotate3DY(-float64(cameraPos / stageWidth * 2 * math.Pi))
-		model := mgl32.Ident4()
-		view := mgl32.LookAt(
-			float32(gCamPos[0]), float32(gCamPos[1]), float32(gCamPos[2]),
-			0, 3, 0, //gCamPos[0], gCamPos[1], gCamPos[2]+1,
-			0, 1, 0)
-		MVP := persp.Mul4(view.Mul4(model))
-
-		for _, b := range gBlocks {
-			b.renderer.Draw(b.pos, MVP)
-		}
-
-		gPaddle.Draw(MVP)
-		gBall.renderer.Draw(gBall.pos, MVP)
+		gStates.Draw(elapsed)
+		gHUD.DrawFPS(elapsed)
+		gPost.End()
 
 		window.SwapBuffers()
 
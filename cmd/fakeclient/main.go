@@ -0,0 +1,78 @@
+// Stand-in for the game when testing a server: connects, plays with random
+// inputs or just watches, and reports what it receives.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/remote"
+	"github.com/CandleEnds/go-breakout/sim"
+	"math/rand"
+	"os"
+	"time"
+)
+
+var (
+	gAddr     = flag.String("addr", "localhost:7778", "server to connect to")
+	gSpectate = flag.Bool("spectate", false, "watch instead of taking a paddle")
+	gDuration = flag.Duration("for", 0, "disconnect after this long, 0 to stay until the server goes")
+	gReport   = flag.Duration("report", time.Second, "interval between reports")
+)
+
+func main() {
+	flag.Parse()
+	client, err := remote.Dial(*gAddr, *gSpectate)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+	if client.Player < 0 {
+		fmt.Println("spectating")
+	} else {
+		fmt.Printf("playing as player %d\n", client.Player+1)
+	}
+
+	ticker := time.NewTicker(sim.TimePerUpdate)
+	defer ticker.Stop()
+	start := time.Now()
+	lastReport, lastReceived := start, 0
+	var steer float64
+	for now := range ticker.C {
+		if err := client.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *gDuration > 0 && now.Sub(start) >= *gDuration {
+			return
+		}
+		if client.Player >= 0 {
+			//wander about, keeping a direction for a while like a person would
+			if rand.Intn(30) == 0 {
+				steer = rand.Float64()*2 - 1
+			}
+			client.SendInput(sim.MakeTickInput(steer, rand.Intn(60) == 0))
+		}
+		if now.Sub(lastReport) >= *gReport {
+			received := client.Received()
+			report(client.Latest(), float64(received-lastReceived)/now.Sub(lastReport).Seconds())
+			lastReport, lastReceived = now, received
+		}
+	}
+}
+
+func report(state *remote.State, bytesPerSecond float64) {
+	if state == nil {
+		fmt.Println("no state yet")
+		return
+	}
+	w := state.World
+	fmt.Printf("tick %d level %d blocks %d", state.Tick, w.Level, len(w.Blocks))
+	for i, p := range w.Players {
+		fmt.Printf("  P%d %d lives %d", i+1, p.Score, p.Lives)
+	}
+	if state.Status == sim.WorldGameOver {
+		fmt.Print("  game over")
+	}
+	fmt.Printf("  %.0f B/s\n", bytesPerSecond)
+}
@@ -0,0 +1,121 @@
+// Drives the gym environment from another process, e.g. a Python trainer,
+// with one JSON object per line on stdin and one reply per line on stdout:
+//
+//	{"cmd": "spec"}                 -> {"actions": 4, "stateSize": 264, "frameWidth": 84, "frameHeight": 112}
+//	{"cmd": "reset", "seed": 1}     -> {"obs": {"state": [...]}}
+//	{"cmd": "step", "action": 3}    -> {"obs": {...}, "reward": 1, "done": false}
+//	{"cmd": "close"}                -> {} and exits
+//
+// Pixels, when asked for with -pixels, come as base64 in obs.pixels. A
+// request that can't be handled gets {"error": "..."}.
+//
+// From Python:
+//
+//	p = subprocess.Popen(["gymbridge"], stdin=PIPE, stdout=PIPE, text=True)
+//	def call(**req):
+//	    p.stdin.write(json.dumps(req) + "\n"); p.stdin.flush()
+//	    return json.loads(p.stdout.readline())
+//	obs = call(cmd="reset", seed=1)["obs"]
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/gym"
+	"os"
+)
+
+var (
+	gState    = flag.Bool("state", true, "observe the state vector")
+	gPixels   = flag.Bool("pixels", false, "observe rendered frames")
+	gWidth    = flag.Int("width", gym.DefaultConfig.FrameWidth, "frame width in pixels")
+	gHeight   = flag.Int("height", gym.DefaultConfig.FrameHeight, "frame height in pixels")
+	gRepeat   = flag.Int("repeat", gym.DefaultConfig.Repeat, "ticks each action is held for")
+	gMaxSteps = flag.Int("maxsteps", gym.DefaultConfig.MaxSteps, "steps before an episode is cut short, 0 for no limit")
+	gGenerate = flag.Bool("generated", false, "play levels generated from each episode's seed")
+)
+
+type request struct {
+	Cmd    string     `json:"cmd"`
+	Seed   int64      `json:"seed"`
+	Action gym.Action `json:"action"`
+}
+
+type reply struct {
+	Obs    *gym.Observation `json:"obs,omitempty"`
+	Reward *float64         `json:"reward,omitempty"`
+	Done   *bool            `json:"done,omitempty"`
+	Error  string           `json:"error,omitempty"`
+
+	Actions     int `json:"actions,omitempty"`
+	StateSize   int `json:"stateSize,omitempty"`
+	FrameWidth  int `json:"frameWidth,omitempty"`
+	FrameHeight int `json:"frameHeight,omitempty"`
+}
+
+func main() {
+	flag.Parse()
+	config := gym.Config{
+		State:       *gState,
+		Pixels:      *gPixels,
+		FrameWidth:  *gWidth,
+		FrameHeight: *gHeight,
+		Repeat:      *gRepeat,
+		MaxSteps:    *gMaxSteps,
+		Generated:   *gGenerate,
+	}
+	env, err := gym.MakeEnv(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	//a state vector with its pixels can be long
+	in.Buffer(make([]byte, 64*1024), 16<<20)
+	out := bufio.NewWriter(os.Stdout)
+	encoder := json.NewEncoder(out)
+	for in.Scan() {
+		var req request
+		var r reply
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			r.Error = err.Error()
+		} else {
+			switch req.Cmd {
+			case "spec":
+				r.Actions = gym.NumActions
+				if config.State {
+					r.StateSize = gym.StateSize
+				}
+				if config.Pixels {
+					r.FrameWidth, r.FrameHeight = config.FrameWidth, config.FrameHeight
+				}
+			case "reset":
+				obs := env.Reset(req.Seed)
+				r.Obs = &obs
+			case "step":
+				if req.Action < 0 || req.Action >= gym.NumActions {
+					r.Error = fmt.Sprintf("action %d out of range 0 to %d", req.Action, gym.NumActions-1)
+					break
+				}
+				obs, reward, done := env.Step(req.Action)
+				r.Obs, r.Reward, r.Done = &obs, &reward, &done
+			case "close":
+				encoder.Encode(r)
+				out.Flush()
+				return
+			default:
+				r.Error = fmt.Sprintf("unknown command %q", req.Cmd)
+			}
+		}
+		encoder.Encode(r)
+		//the trainer waits for each reply before sending more
+		out.Flush()
+	}
+	if err := in.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+// Prints generated levels in the format the game loads with -level, so a
+// good one can be kept, tweaked or passed on by its seed.
+//
+//	levelgen -seed 42 -level 3 > maze.json
+//	breakout -level maze.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	"os"
+)
+
+var (
+	gSeed    = flag.Int64("seed", 1, "generator seed")
+	gLevel   = flag.Int("level", 1, "level number, later levels are harder")
+	gCount   = flag.Int("count", 1, "with -preview, show this many levels from -level on")
+	gPreview = flag.Bool("preview", false, "draw the levels as text instead of saving one")
+)
+
+func main() {
+	flag.Parse()
+	if !*gPreview {
+		if err := sim.GenerateLevel(*gSeed, *gLevel).Save(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	for n := *gLevel; n < *gLevel+*gCount; n++ {
+		l := sim.GenerateLevel(*gSeed, n)
+		fmt.Println(l.Name)
+		for _, row := range l.Rows {
+			fmt.Println("  " + row)
+		}
+		for _, m := range l.Moves {
+			fmt.Printf("  row %d %s %.2f\n", m.Row, m.Kind, m.Speed)
+		}
+		for _, r := range l.Rings {
+			fmt.Printf("  ring of %d at %.1f,%.1f\n", r.Blocks, r.Col, r.Row)
+		}
+		fmt.Println()
+	}
+}
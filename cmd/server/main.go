@@ -0,0 +1,48 @@
+// Dedicated game server. Runs the game without a window and lets players
+// and spectators connect to it with the game's -server flag.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/remote"
+	"github.com/CandleEnds/go-breakout/sim"
+	"os"
+	"os/signal"
+)
+
+var (
+	gAddr = flag.String("addr", ":7778", "address to listen on")
+	gMode = flag.String("mode", "coop", "game mode: single, coop or versus")
+	gSeed = flag.Int64("seed", 0, "play levels generated from this seed, 0 for the classic layout")
+)
+
+func main() {
+	flag.Parse()
+	mode, err := sim.ParseGameMode(*gMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var levels sim.LevelSource = sim.ClassicLevels{}
+	if *gSeed != 0 {
+		levels = sim.GeneratedLevels{Seed: *gSeed}
+	}
+	server, err := remote.Listen(*gAddr, mode, sim.DefaultStageSize, levels)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("serving %v on %v\n", *gMode, server.Addr())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		server.Close()
+	}()
+	if err := server.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
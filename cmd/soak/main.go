@@ -0,0 +1,210 @@
+// Soak test for the physics: bots play game after game as fast as the
+// simulation runs, while every tick is checked for balls escaping the
+// stage, broken velocities, stuck rallies and lost points.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"os"
+	"time"
+)
+
+var (
+	gGames = flag.Int("games", 20, "number of games to play")
+	gMode  = flag.String("mode", "coop", "game mode: single, coop or versus")
+	gSkill = flag.String("skill", "HARD", "bot skill: EASY, MEDIUM or HARD")
+	gSeed  = flag.Int64("seed", 1, "seed for the first game, each game after adds one")
+	//mazes and odd shapes find different trouble to the classic layout
+	gGenerated = flag.Bool("generated", false, "play levels generated from each game's seed")
+	gPressure  = flag.Bool("pressure", false, "play pressure mode, rows generated from each game's seed")
+	//an hour of play at 60 ticks a second
+	gMaxTicks = flag.Int("ticks", 60*60*60, "give up on a game after this many ticks")
+	//balls that haven't touched anything for this long are stuck
+	gStuckTicks = flag.Int("stuck", 60*60, "ticks without a paddle hit or broken block before the balls count as stuck")
+)
+
+type result struct {
+	ticks    int
+	level    int
+	score    int
+	problems []string
+}
+
+func main() {
+	flag.Parse()
+	mode, err := sim.ParseGameMode(*gMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var skill *sim.BotSkill
+	for i := range sim.BotSkills {
+		if sim.BotSkills[i].Name == *gSkill {
+			skill = &sim.BotSkills[i]
+		}
+	}
+	if skill == nil {
+		fmt.Fprintf(os.Stderr, "unknown skill %q\n", *gSkill)
+		os.Exit(2)
+	}
+
+	failed := 0
+	totalTicks := 0
+	start := time.Now()
+	for game := 0; game < *gGames; game++ {
+		seed := *gSeed + int64(game)
+		r := play(mode, *skill, seed)
+		totalTicks += r.ticks
+		fmt.Printf("game %d seed %d: %d ticks, level %d, score %d\n", game+1, seed, r.ticks, r.level, r.score)
+		for _, p := range r.problems {
+			fmt.Println("  " + p)
+		}
+		if len(r.problems) > 0 {
+			failed++
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("%d of %d games had problems, %d ticks in %v (%.0fx real time)\n",
+		failed, *gGames, totalTicks, elapsed.Round(time.Millisecond),
+		float64(totalTicks)*sim.TimePerUpdate.Seconds()/elapsed.Seconds())
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func play(mode sim.GameMode, skill sim.BotSkill, seed int64) result {
+	w := sim.MakeWorld(sim.DefaultStageSize, mode)
+	if *gGenerated {
+		w.SetLevels(sim.GeneratedLevels{Seed: seed})
+	}
+	if *gPressure {
+		pressure := sim.DefaultPressure
+		pressure.Rows = sim.GeneratedRows{Seed: seed}
+		w.SetPressure(&pressure)
+	}
+	for i, p := range w.Players {
+		p.Paddle.SetController(sim.MakeBot(w, i, skill, seed*int64(len(w.Players))+int64(i)))
+	}
+	var r result
+	problem := func(format string, args ...interface{}) {
+		//the first few are enough to go on
+		if len(r.problems) < 10 {
+			r.problems = append(r.problems, fmt.Sprintf("tick %d: ", r.ticks)+fmt.Sprintf(format, args...))
+		}
+	}
+	//ticks since a ball last hit a paddle or broke a block
+	quiet := 0
+	destroyed := 0
+	//where each ball last came down past the top of the paddles
+	crossed := make([]crossing, len(w.Players))
+	for ; r.ticks < *gMaxTicks; r.ticks++ {
+		above := make([]bool, len(w.Players))
+		for i, p := range w.Players {
+			above[i] = p.Ball.GetPos()[1] > paddleTop(w)
+		}
+		status := w.Update()
+		for i, p := range w.Players {
+			if above[i] && !p.Serving && p.Ball.GetPos()[1] <= paddleTop(w) {
+				crossed[i] = makeCrossing(w, p.Ball)
+			}
+		}
+		quiet++
+		for _, e := range w.TakeEvents() {
+			switch e.Kind {
+			case sim.EventBlockDestroyed:
+				destroyed++
+				quiet = 0
+			case sim.EventPaddleHit:
+				quiet = 0
+			case sim.EventBallLost:
+				crossed[e.Player].check(w, e.Player, problem)
+			}
+		}
+		inPlay := false
+		for i, p := range w.Players {
+			checkBall(i, p, problem)
+			inPlay = inPlay || !(p.Out() || p.Serving)
+		}
+		if !inPlay {
+			quiet = 0
+		}
+		if quiet == *gStuckTicks {
+			for i, p := range w.Players {
+				problem("P%d ball stuck at %v going %v", i+1, p.Ball.Pos, p.Ball.Velocity)
+			}
+		}
+		if w.Score() != destroyed*sim.BlockScore {
+			problem("score %d for %d blocks destroyed", w.Score(), destroyed)
+			destroyed = w.Score() / sim.BlockScore
+		}
+
+		if status == sim.WorldLevelComplete {
+			w.NextLevel()
+		}
+		if status == sim.WorldGameOver {
+			break
+		}
+	}
+	r.level = w.Level
+	r.score = w.Score()
+	return r
+}
+
+func checkBall(i int, p *sim.Player, problem func(string, ...interface{})) {
+	//a served ball sits on its paddle, which can hang over the seam
+	if p.Out() || p.Serving {
+		return
+	}
+	b := p.Ball
+	for _, v := range []float64{b.Pos[0], b.Pos[1], b.Velocity[0], b.Velocity[1]} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			problem("P%d ball at %v going %v", i+1, b.Pos, b.Velocity)
+			return
+		}
+	}
+	//collisions can push it out a little before the next Update wraps it
+	margin := b.Size[0]
+	if b.Pos[0] < -margin || b.Pos[0] > sim.DefaultStageSize[0]+margin || b.Pos[1] < -margin || b.Pos[1]+b.Size[1] > sim.DefaultStageSize[1]+margin {
+		problem("P%d ball outside the stage at %v", i+1, b.Pos)
+	}
+	if speed := b.Velocity.Len(); math.Abs(speed-1) > 1e-6 {
+		problem("P%d ball velocity %v has length %v", i+1, b.Velocity, speed)
+	}
+}
+
+// Paddles all share the bottom row
+func paddleTop(w *sim.World) float64 {
+	p := w.Players[0].Paddle
+	return p.Pos[1] + p.Size[1]
+}
+
+// A ball coming down past the top of the paddles, and where the paddles
+// were when it did
+type crossing struct {
+	x       float64
+	radius  float64
+	paddles []mgl.Vec2
+}
+
+func makeCrossing(w *sim.World, b *sim.Ball) crossing {
+	c := crossing{x: b.Center()[0], radius: b.GetSize()[0] / 2}
+	for _, p := range w.Players {
+		c.paddles = append(c.paddles, mgl.Vec2{p.Paddle.Center()[0], p.Paddle.Size[0]})
+	}
+	return c
+}
+
+// A lost ball should have missed every paddle on its way down, wherever on
+// the cylinder they were
+func (c crossing) check(w *sim.World, player int, problem func(string, ...interface{})) {
+	for i, p := range c.paddles {
+		d := w.WrapDelta(p[0], c.x)
+		if math.Abs(d) < p[1]/2-c.radius {
+			problem("P%d ball fell through P%d paddle at x %.3f, paddle centre %.3f", player+1, i+1, c.x, p[0])
+		}
+	}
+}
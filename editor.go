@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var gEditFile = flag.String("edit", "", "open the level editor on this file, which is made if it doesn't exist")
+
+// Columns of a new level, the same as the classic layout
+const EditorNewCols = 10
+
+var (
+	EditorStageColor  = mgl.Vec4{0, 0, 0, 0.6}
+	EditorGridColor   = mgl.Vec4{1, 1, 1, 0.15}
+	EditorCursorColor = mgl.Vec4{1, 1, 1, 0.4}
+	EditorDigitColor  = mgl.Vec4{0, 0, 0, 0.7}
+)
+
+// Paints a level onto a grid with the mouse. The stage is shown flat on
+// the left half of the window and wrapped round the cylinder on the right.
+type EditorState struct {
+	file      string
+	stageSize mgl.Vec2
+	//kept up to date with cells, settings are changed on it directly
+	level *sim.Level
+	//LevelMaxRows rows being painted, empty ones at the bottom are left
+	//out of the level
+	cells [][]byte
+	//what a left click paints
+	brush byte
+	//the level laid out on a stage, for drawing
+	preview *sim.World
+	view    *WorldView
+	//cell under the cursor, -1 when it's off the grid
+	hoverRow int
+	hoverCol int
+	//changes since the last save, and whether leaving has been warned about
+	dirty  bool
+	warned bool
+	//result of the last save or test
+	message string
+}
+
+// Edit the level in file, or a new empty one named after it
+func MakeEditorState(stageSize mgl.Vec2, file string) (*EditorState, error) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	level := &sim.Level{
+		Name: strings.ToUpper(name),
+		Rows: []string{strings.Repeat(string(sim.CellEmpty), EditorNewCols)},
+	}
+	f, err := os.Open(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		if level, err = sim.LoadLevel(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	e := &EditorState{
+		file:      file,
+		stageSize: stageSize,
+		level:     level,
+		brush:     '1',
+		view:      MakeWorldView(),
+		hoverRow:  -1,
+		hoverCol:  -1,
+	}
+	e.cells = make([][]byte, sim.LevelMaxRows)
+	for r := range e.cells {
+		if r < len(level.Rows) {
+			e.cells[r] = []byte(level.Rows[r])
+		} else {
+			e.cells[r] = []byte(strings.Repeat(string(sim.CellEmpty), level.Cols()))
+		}
+	}
+	e.rebuild()
+	return e, nil
+}
+
+func emptyRow(row []byte) bool {
+	for _, c := range row {
+		if c != sim.CellEmpty {
+			return false
+		}
+	}
+	return true
+}
+
+// Bring the level and its preview up to date with the cells
+func (e *EditorState) rebuild() {
+	//empty rows off the bottom go, unless a motion is set on them
+	kept := 1
+	for _, m := range e.level.Moves {
+		kept = sim.MaxInt(kept, m.Row+1)
+	}
+	rows := len(e.cells)
+	for rows > kept && emptyRow(e.cells[rows-1]) {
+		rows--
+	}
+	//a new slice, levels handed to test games keep the old one
+	e.level.Rows = nil
+	for _, row := range e.cells[:rows] {
+		e.level.Rows = append(e.level.Rows, string(row))
+	}
+	e.preview = sim.MakeWorld(e.stageSize, sim.ModeSingle)
+	e.preview.SetLevels(sim.LevelList{e.level})
+	player := e.preview.Players[0]
+	player.Ball.Serve(player.Paddle)
+}
+
+func (e *EditorState) changed() {
+	e.dirty = true
+	e.warned = false
+	e.message = ""
+	e.rebuild()
+}
+
+func (e *EditorState) paint(row, col int, c byte) {
+	if e.cells[row][col] == c {
+		return
+	}
+	e.cells[row][col] = c
+	e.changed()
+}
+
+// Add or drop columns on the right
+func (e *EditorState) setCols(cols int) {
+	for r, row := range e.cells {
+		for len(row) < cols {
+			row = append(row, sim.CellEmpty)
+		}
+		e.cells[r] = row[:cols]
+	}
+	e.changed()
+}
+
+// Top left of the flat stage on screen, and screen units per stage unit.
+// It fills the left half of the window between the labels.
+func (e *EditorState) layout() (origin mgl.Vec2, scale float64) {
+	screen := gHUD.ScreenSize()
+	line := gHUD.text.LineHeight()
+	origin = mgl.Vec2{HUDMargin, HUDMargin + 2*line}
+	width := screen[0]/2 - 1.5*HUDMargin
+	height := screen[1] - origin[1] - 4*line - HUDMargin
+	scale = math.Min(width/e.stageSize[0], height/e.stageSize[1])
+	return origin, scale
+}
+
+// Screen position of the top left of a stage rectangle
+func (e *EditorState) toScreen(pos, size mgl.Vec2) mgl.Vec2 {
+	origin, scale := e.layout()
+	return origin.Add(mgl.Vec2{pos[0] * scale, (e.stageSize[1] - pos[1] - size[1]) * scale})
+}
+
+// Grid cell at a screen position
+func (e *EditorState) cellAt(p mgl.Vec2) (row, col int, ok bool) {
+	origin, scale := e.layout()
+	cell := e.level.CellSize(e.stageSize).Mul(scale)
+	local := p.Sub(origin)
+	if local[0] < 0 || local[1] < 0 {
+		return -1, -1, false
+	}
+	row, col = int(local[1]/cell[1]), int(local[0]/cell[0])
+	if row >= len(e.cells) || col >= e.level.Cols() {
+		return -1, -1, false
+	}
+	return row, col, true
+}
+
+func (e *EditorState) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	if action != glfw.Press {
+		return false
+	}
+	switch {
+	case key >= glfw.Key1 && key <= glfw.Key9:
+		e.brush = byte('1' + key - glfw.Key1)
+	case key == glfw.Key0:
+		e.brush = sim.CellSolid
+	case gInput.Is(ActionEditSave, key, mods):
+		e.save()
+	case gInput.Is(ActionEditTest, key, mods):
+		e.test()
+	case gInput.Is(ActionEditMenu, key, mods):
+		gStates.Push(MakeLevelMenuState(e))
+	case gInput.Is(ActionBack, key, mods):
+		e.leave()
+	default:
+		return false
+	}
+	return true
+}
+
+func (e *EditorState) save() {
+	if err := e.level.Validate(); err != nil {
+		e.message = strings.ToUpper(err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	err := e.level.Save(&buf)
+	if err == nil {
+		//written whole, so a failed save leaves the old file alone
+		err = ioutil.WriteFile(e.file, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		fmt.Println("saving level:", err)
+		e.message = "COULD NOT SAVE"
+		return
+	}
+	e.dirty = false
+	e.warned = false
+	e.message = "SAVED " + strings.ToUpper(filepath.Base(e.file))
+}
+
+// Play the level as it is now, coming back here afterwards
+func (e *EditorState) test() {
+	if err := e.level.Validate(); err != nil {
+		e.message = strings.ToUpper(err.Error())
+		return
+	}
+	level := *e.level
+	play, err := MakeTestPlayState(e.stageSize, &level)
+	if err != nil {
+		fmt.Println(err)
+		e.message = "COULD NOT START TEST"
+		return
+	}
+	e.message = ""
+	gStates.Push(play)
+}
+
+// Back to the title, after a warning if there are unsaved changes
+func (e *EditorState) leave() {
+	if e.dirty && !e.warned {
+		e.warned = true
+		e.message = "UNSAVED - " + strings.ToUpper(gInput.Describe(ActionBack)) + " AGAIN TO LEAVE"
+		return
+	}
+	gStates.Pop()
+}
+
+// Paint while a button is held, left with the brush and right to erase.
+// The preview turns to show the column under the cursor.
+func (e *EditorState) Update() {
+	e.hoverRow, e.hoverCol = -1, -1
+	if pos, ok := gMouse.Pos(); ok {
+		if row, col, ok := e.cellAt(pos); ok {
+			e.hoverRow, e.hoverCol = row, col
+			switch {
+			case gMouse.Held(glfw.MouseButtonLeft):
+				e.paint(row, col, e.brush)
+			case gMouse.Held(glfw.MouseButtonRight):
+				e.paint(row, col, sim.CellEmpty)
+			}
+		}
+	}
+	x := e.stageSize[0] / 2
+	if e.hoverCol >= 0 {
+		x = (float64(e.hoverCol) + 0.5) * e.level.CellSize(e.stageSize)[0]
+	}
+	e.view.Face(e.preview, x)
+}
+
+func (e *EditorState) Draw(elapsed time.Duration) {
+	if gScreen.fbHeight > 0 {
+		half := gScreen.fbWidth / 2
+		SetViewportRect(half, 0, gScreen.fbWidth-half, gScreen.fbHeight)
+		e.view.aspect = float64(gScreen.fbWidth-half) / float64(gScreen.fbHeight)
+		e.view.Draw(e.preview)
+		SetViewport(gScreen.fbWidth, gScreen.fbHeight)
+	}
+
+	gHUD.Begin()
+	defer gHUD.End()
+	e.drawGrid()
+
+	line := gHUD.text.LineHeight()
+	name := e.level.Name
+	if e.dirty {
+		name += "*"
+	}
+	gHUD.LabelAt(name, mgl.Vec2{HUDMargin, HUDMargin}, MenuTitleColor)
+	brush := "BRUSH SOLID"
+	if e.brush != sim.CellSolid {
+		brush = "BRUSH " + string(e.brush)
+	}
+	gHUD.LabelAt(brush, mgl.Vec2{HUDMargin, HUDMargin + line}, HUDWhite)
+
+	origin, scale := e.layout()
+	settings := fmt.Sprintf("BALL X%.2f\nPADDLE %.2f", e.level.BallScale(), e.level.PaddleSpan())
+	gHUD.LabelAt(settings, origin.Add(mgl.Vec2{0, e.stageSize[1]*scale + HUDMargin}), HUDWhite)
+
+	help := fmt.Sprintf("L PAINT  R ERASE  1-9/0 BRUSH\n%s TEST  %s SAVE  %s LEVEL",
+		gInput.Describe(ActionEditTest), gInput.Describe(ActionEditSave), gInput.Describe(ActionEditMenu))
+	gHUD.Label(strings.ToUpper(help), AnchorBottomLeft, HUDWhite)
+	if e.message != "" {
+		screen := gHUD.ScreenSize()
+		gHUD.LabelAt(e.message, mgl.Vec2{HUDMargin, screen[1] - HUDMargin - 3*line}, MenuTitleColor)
+	}
+}
+
+// The stage from the front, unwrapped, with the cells that can be painted
+// ruled over it
+func (e *EditorState) drawGrid() {
+	origin, scale := e.layout()
+	gHUD.Rect(origin, e.stageSize.Mul(scale), EditorStageColor)
+	for _, b := range e.preview.Blocks {
+		gHUD.Rect(e.toScreen(b.Pos, b.Size), b.Size.Mul(scale), b.Color)
+	}
+
+	cols := e.level.Cols()
+	cell := e.level.CellSize(e.stageSize).Mul(scale)
+	//hits left on each block, where the digits fit
+	digit := gHUD.text.Measure("0")
+	if digit[0] < cell[0] && digit[1] <= cell[1] {
+		P := gHUD.Projection()
+		for r, row := range e.cells {
+			for c, cellType := range row {
+				if cellType >= '1' && cellType <= '9' {
+					pos := origin.Add(mgl.Vec2{float64(c) * cell[0], float64(r) * cell[1]})
+					pos = pos.Add(cell.Sub(digit).Mul(0.5))
+					gHUD.text.Draw(string(cellType), pos, EditorDigitColor, P)
+				}
+			}
+		}
+	}
+
+	grid := mgl.Vec2{float64(cols) * cell[0], float64(len(e.cells)) * cell[1]}
+	for c := 0; c <= cols; c++ {
+		gHUD.Rect(origin.Add(mgl.Vec2{float64(c) * cell[0], 0}), mgl.Vec2{1, grid[1]}, EditorGridColor)
+	}
+	for r := 0; r <= len(e.cells); r++ {
+		gHUD.Rect(origin.Add(mgl.Vec2{0, float64(r) * cell[1]}), mgl.Vec2{grid[0], 1}, EditorGridColor)
+	}
+	if e.hoverRow >= 0 {
+		pos := origin.Add(mgl.Vec2{float64(e.hoverCol) * cell[0], float64(e.hoverRow) * cell[1]})
+		gHUD.Rect(pos, cell, EditorCursorColor)
+	}
+
+	//the paddle, split where it wraps round the back
+	player := e.preview.Players[0]
+	paddle := player.Paddle
+	width := math.Min(paddle.Size[0], e.stageSize[0]-paddle.Pos[0])
+	gHUD.Rect(e.toScreen(paddle.Pos, paddle.Size), mgl.Vec2{width, paddle.Size[1]}.Mul(scale), PlayerColors[0])
+	if rest := paddle.Size[0] - width; rest > 0 {
+		pos := mgl.Vec2{0, paddle.Pos[1]}
+		gHUD.Rect(e.toScreen(pos, paddle.Size), mgl.Vec2{rest, paddle.Size[1]}.Mul(scale), PlayerColors[0])
+	}
+	ball := player.Ball
+	gHUD.Rect(e.toScreen(ball.Pos, ball.Size), ball.Size.Mul(scale), HUDWhite)
+}
+
+func (e *EditorState) IsOverlay() bool {
+	return false
+}
+
+// Settings for the level being edited, each item steps through its values
+func MakeLevelMenuState(e *EditorState) *MenuState {
+	s := &MenuState{overlay: true}
+	s.menu.title = "LEVEL"
+	s.menu.items = []MenuItem{
+		{
+			func() string { return fmt.Sprintf("COLUMNS %d", e.level.Cols()) },
+			func() { e.setCols(e.level.Cols()%sim.LevelMaxCols + 1) },
+		},
+		{
+			func() string { return fmt.Sprintf("BALL SPEED X%.2f", e.level.BallScale()) },
+			func() {
+				//quarter steps, 1 being the usual speed
+				speed := e.level.BallScale() + 0.25
+				if speed > sim.MaxBallSpeed {
+					speed = sim.MinBallSpeed
+				}
+				if speed == 1 {
+					speed = 0
+				}
+				e.level.BallSpeed = speed
+				e.changed()
+			},
+		},
+		{
+			func() string { return fmt.Sprintf("PADDLE WIDTH %.2f", e.level.PaddleSpan()) },
+			func() {
+				width := math.Round(e.level.PaddleSpan()*10+1) / 10
+				if width > sim.MaxPaddleWidth {
+					width = sim.MinPaddleWidth
+				}
+				if width == sim.DefaultPaddleWidth {
+					width = 0
+				}
+				e.level.PaddleWidth = width
+				e.changed()
+			},
+		},
+		{Label("BACK"), gStates.Pop},
+	}
+	s.back = gStates.Pop
+	return s
+}
@@ -0,0 +1,524 @@
+package main
+
+// Built-in 5x7 bitmap font, rasterised into a texture atlas by MakeBitmapFont.
+// Lowercase letters are drawn with the uppercase glyphs.
+const (
+	FontGlyphWidth  = 5
+	FontGlyphHeight = 7
+)
+
+var fontGlyphs = map[rune][FontGlyphHeight]string{
+	' ': {
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+	},
+	'A': {
+		" ### ",
+		"#   #",
+		"#   #",
+		"#####",
+		"#   #",
+		"#   #",
+		"#   #",
+	},
+	'B': {
+		"#### ",
+		"#   #",
+		"#   #",
+		"#### ",
+		"#   #",
+		"#   #",
+		"#### ",
+	},
+	'C': {
+		" ### ",
+		"#   #",
+		"#    ",
+		"#    ",
+		"#    ",
+		"#   #",
+		" ### ",
+	},
+	'D': {
+		"#### ",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#### ",
+	},
+	'E': {
+		"#####",
+		"#    ",
+		"#    ",
+		"#### ",
+		"#    ",
+		"#    ",
+		"#####",
+	},
+	'F': {
+		"#####",
+		"#    ",
+		"#    ",
+		"#### ",
+		"#    ",
+		"#    ",
+		"#    ",
+	},
+	'G': {
+		" ### ",
+		"#   #",
+		"#    ",
+		"# ###",
+		"#   #",
+		"#   #",
+		" ####",
+	},
+	'H': {
+		"#   #",
+		"#   #",
+		"#   #",
+		"#####",
+		"#   #",
+		"#   #",
+		"#   #",
+	},
+	'I': {
+		" ### ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		" ### ",
+	},
+	'J': {
+		"  ###",
+		"   # ",
+		"   # ",
+		"   # ",
+		"   # ",
+		"#  # ",
+		" ##  ",
+	},
+	'K': {
+		"#   #",
+		"#  # ",
+		"# #  ",
+		"##   ",
+		"# #  ",
+		"#  # ",
+		"#   #",
+	},
+	'L': {
+		"#    ",
+		"#    ",
+		"#    ",
+		"#    ",
+		"#    ",
+		"#    ",
+		"#####",
+	},
+	'M': {
+		"#   #",
+		"## ##",
+		"# # #",
+		"# # #",
+		"#   #",
+		"#   #",
+		"#   #",
+	},
+	'N': {
+		"#   #",
+		"#   #",
+		"##  #",
+		"# # #",
+		"#  ##",
+		"#   #",
+		"#   #",
+	},
+	'O': {
+		" ### ",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'P': {
+		"#### ",
+		"#   #",
+		"#   #",
+		"#### ",
+		"#    ",
+		"#    ",
+		"#    ",
+	},
+	'Q': {
+		" ### ",
+		"#   #",
+		"#   #",
+		"#   #",
+		"# # #",
+		"#  # ",
+		" ## #",
+	},
+	'R': {
+		"#### ",
+		"#   #",
+		"#   #",
+		"#### ",
+		"# #  ",
+		"#  # ",
+		"#   #",
+	},
+	'S': {
+		" ####",
+		"#    ",
+		"#    ",
+		" ### ",
+		"    #",
+		"    #",
+		"#### ",
+	},
+	'T': {
+		"#####",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+	},
+	'U': {
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'V': {
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		" # # ",
+		"  #  ",
+	},
+	'W': {
+		"#   #",
+		"#   #",
+		"#   #",
+		"# # #",
+		"# # #",
+		"# # #",
+		" # # ",
+	},
+	'X': {
+		"#   #",
+		"#   #",
+		" # # ",
+		"  #  ",
+		" # # ",
+		"#   #",
+		"#   #",
+	},
+	'Y': {
+		"#   #",
+		"#   #",
+		" # # ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+	},
+	'Z': {
+		"#####",
+		"    #",
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#    ",
+		"#####",
+	},
+	'0': {
+		" ### ",
+		"#   #",
+		"#  ##",
+		"# # #",
+		"##  #",
+		"#   #",
+		" ### ",
+	},
+	'1': {
+		"  #  ",
+		" ##  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		" ### ",
+	},
+	'2': {
+		" ### ",
+		"#   #",
+		"    #",
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#####",
+	},
+	'3': {
+		"#####",
+		"   # ",
+		"  #  ",
+		"   # ",
+		"    #",
+		"#   #",
+		" ### ",
+	},
+	'4': {
+		"   # ",
+		"  ## ",
+		" # # ",
+		"#  # ",
+		"#####",
+		"   # ",
+		"   # ",
+	},
+	'5': {
+		"#####",
+		"#    ",
+		"#### ",
+		"    #",
+		"    #",
+		"#   #",
+		" ### ",
+	},
+	'6': {
+		"  ## ",
+		" #   ",
+		"#    ",
+		"#### ",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'7': {
+		"#####",
+		"    #",
+		"   # ",
+		"  #  ",
+		" #   ",
+		" #   ",
+		" #   ",
+	},
+	'8': {
+		" ### ",
+		"#   #",
+		"#   #",
+		" ### ",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'9': {
+		" ### ",
+		"#   #",
+		"#   #",
+		" ####",
+		"    #",
+		"   # ",
+		" ##  ",
+	},
+	'.': {
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		" ##  ",
+		" ##  ",
+	},
+	',': {
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		" ##  ",
+		"  #  ",
+		" #   ",
+	},
+	':': {
+		"     ",
+		" ##  ",
+		" ##  ",
+		"     ",
+		" ##  ",
+		" ##  ",
+		"     ",
+	},
+	'!': {
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"     ",
+		"  #  ",
+	},
+	'?': {
+		" ### ",
+		"#   #",
+		"    #",
+		"   # ",
+		"  #  ",
+		"     ",
+		"  #  ",
+	},
+	'-': {
+		"     ",
+		"     ",
+		"     ",
+		"#####",
+		"     ",
+		"     ",
+		"     ",
+	},
+	'+': {
+		"     ",
+		"  #  ",
+		"  #  ",
+		"#####",
+		"  #  ",
+		"  #  ",
+		"     ",
+	},
+	'=': {
+		"     ",
+		"     ",
+		"#####",
+		"     ",
+		"#####",
+		"     ",
+		"     ",
+	},
+	'/': {
+		"     ",
+		"    #",
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#    ",
+		"     ",
+	},
+	'\'': {
+		" ##  ",
+		"  #  ",
+		" #   ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+	},
+	'(': {
+		"   # ",
+		"  #  ",
+		" #   ",
+		" #   ",
+		" #   ",
+		"  #  ",
+		"   # ",
+	},
+	')': {
+		" #   ",
+		"  #  ",
+		"   # ",
+		"   # ",
+		"   # ",
+		"  #  ",
+		" #   ",
+	},
+	'<': {
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#    ",
+		" #   ",
+		"  #  ",
+		"   # ",
+	},
+	'>': {
+		" #   ",
+		"  #  ",
+		"   # ",
+		"    #",
+		"   # ",
+		"  #  ",
+		" #   ",
+	},
+	'_': {
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"     ",
+		"#####",
+	},
+	'%': {
+		"##   ",
+		"##  #",
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#  ##",
+		"   ##",
+	},
+	'*': {
+		"     ",
+		"  #  ",
+		"# # #",
+		" ### ",
+		"# # #",
+		"  #  ",
+		"     ",
+	},
+	'#': {
+		" # # ",
+		" # # ",
+		"#####",
+		" # # ",
+		"#####",
+		" # # ",
+		" # # ",
+	},
+	'[': {
+		" ### ",
+		" #   ",
+		" #   ",
+		" #   ",
+		" #   ",
+		" #   ",
+		" ### ",
+	},
+	']': {
+		" ### ",
+		"   # ",
+		"   # ",
+		"   # ",
+		"   # ",
+		"   # ",
+		" ### ",
+	},
+}
@@ -0,0 +1,10 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D Sampler;
+uniform vec4 tint;
+
+void main()
+{
+   gl_FragColor = tint * texture2D(Sampler, TexCoordOut);
+}
\ No newline at end of file
@@ -0,0 +1,12 @@
+#version 330
+
+uniform sampler2D tex;
+uniform vec4 tint;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   outputColor = tint * texture(tex, fragTexCoord);
+}
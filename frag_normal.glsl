@@ -1,11 +1,63 @@
 #version 120
+
+#define MAX_POINT_LIGHTS 4
+
 varying vec2 TexCoordOut;
 varying float normPosOut;
+varying vec3 NormalOut;
+varying vec3 WorldPosOut;
 
 uniform sampler2D Sampler;
+uniform vec4 tint;
+
+//Lighting, all positions and directions in world space
+uniform vec3 ambientColor;
+//Towards the directional light
+uniform vec3 lightDirection;
+uniform vec3 lightColor;
+uniform int numPointLights;
+uniform vec3 pointLightPos[MAX_POINT_LIGHTS];
+uniform vec3 pointLightColor[MAX_POINT_LIGHTS];
+uniform float pointLightRange[MAX_POINT_LIGHTS];
+uniform vec3 eyePos;
+uniform float specularStrength;
+uniform float shininess;
+
+//Blinn-Phong, returns diffuse in rgb and specular in a
+vec4 shade(vec3 n, vec3 toLight, vec3 toEye)
+{
+   float diffuse = max(dot(n, toLight), 0.0);
+   float specular = 0.0;
+   if (diffuse > 0.0) {
+      vec3 halfway = normalize(toLight + toEye);
+      specular = pow(max(dot(n, halfway), 0.0), shininess) * specularStrength;
+   }
+   return vec4(diffuse, diffuse, diffuse, specular);
+}
 
 void main()
 {
    //gl_FragColor = vec4(0.0, 1.0, 0.0, 1.0);
-   gl_FragColor = texture2D(Sampler, TexCoordOut) + vec4(normPosOut, 0.0, normPosOut, 0.0);
+   vec4 albedo = tint * texture2D(Sampler, TexCoordOut) + vec4(normPosOut, 0.0, normPosOut, 0.0);
+
+   vec3 n = normalize(NormalOut);
+   vec3 toEye = normalize(eyePos - WorldPosOut);
+
+   vec4 sun = shade(n, normalize(lightDirection), toEye);
+   vec3 diffuse = ambientColor + sun.rgb * lightColor;
+   vec3 specular = sun.a * lightColor;
+
+   //constant loop bound, 1.20 drivers can't always loop to a uniform
+   for (int i = 0; i < MAX_POINT_LIGHTS; i++) {
+      if (i < numPointLights) {
+         vec3 toLight = pointLightPos[i] - WorldPosOut;
+         float falloff = clamp(1.0 - length(toLight) / pointLightRange[i], 0.0, 1.0);
+         falloff *= falloff;
+         vec4 point = shade(n, normalize(toLight), toEye) * falloff;
+         diffuse += point.rgb * pointLightColor[i];
+         specular += point.a * pointLightColor[i];
+      }
+   }
+
+   gl_FragColor = vec4(albedo.rgb * diffuse + specular, albedo.a);
 }
\ No newline at end of file
@@ -1,13 +1,60 @@
 #version 330
 
+#define MAX_POINT_LIGHTS 4
+
 uniform sampler2D tex;
+uniform vec4 tint;
+
+//Lighting, all positions and directions in world space
+uniform vec3 ambientColor;
+//Towards the directional light
+uniform vec3 lightDirection;
+uniform vec3 lightColor;
+uniform int numPointLights;
+uniform vec3 pointLightPos[MAX_POINT_LIGHTS];
+uniform vec3 pointLightColor[MAX_POINT_LIGHTS];
+uniform float pointLightRange[MAX_POINT_LIGHTS];
+uniform vec3 eyePos;
+uniform float specularStrength;
+uniform float shininess;
 
 in vec2 fragTexCoord;
 in float normPosOut;
+in vec3 fragNormal;
+in vec3 fragWorldPos;
 
 out vec4 outputColor;
 
+//Blinn-Phong, returns diffuse in rgb and specular in a
+vec4 shade(vec3 n, vec3 toLight, vec3 toEye) {
+   float diffuse = max(dot(n, toLight), 0.0);
+   float specular = 0.0;
+   if (diffuse > 0.0) {
+      vec3 halfway = normalize(toLight + toEye);
+      specular = pow(max(dot(n, halfway), 0.0), shininess) * specularStrength;
+   }
+   return vec4(diffuse, diffuse, diffuse, specular);
+}
+
 void main() {
    vec4 additive = vec4(normPosOut, 0.0, 0.0, 0.0);
-   outputColor = texture(tex, fragTexCoord); // + additive
+   vec4 albedo = tint * texture(tex, fragTexCoord); // + additive
+
+   vec3 n = normalize(fragNormal);
+   vec3 toEye = normalize(eyePos - fragWorldPos);
+
+   vec4 sun = shade(n, normalize(lightDirection), toEye);
+   vec3 diffuse = ambientColor + sun.rgb * lightColor;
+   vec3 specular = sun.a * lightColor;
+
+   for (int i = 0; i < numPointLights && i < MAX_POINT_LIGHTS; i++) {
+      vec3 toLight = pointLightPos[i] - fragWorldPos;
+      float falloff = clamp(1.0 - length(toLight) / pointLightRange[i], 0.0, 1.0);
+      falloff *= falloff;
+      vec4 point = shade(n, normalize(toLight), toEye) * falloff;
+      diffuse += point.rgb * pointLightColor[i];
+      specular += point.a * pointLightColor[i];
+   }
+
+   outputColor = vec4(albedo.rgb * diffuse + specular, albedo.a);
 }
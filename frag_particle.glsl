@@ -0,0 +1,10 @@
+#version 120
+varying vec2 TexCoordOut;
+varying vec4 ColorOut;
+
+uniform sampler2D Sampler;
+
+void main()
+{
+   gl_FragColor = ColorOut * texture2D(Sampler, TexCoordOut);
+}
\ No newline at end of file
@@ -0,0 +1,12 @@
+#version 330
+
+uniform sampler2D tex;
+
+in vec2 fragTexCoord;
+in vec4 fragColor;
+
+out vec4 outputColor;
+
+void main() {
+   outputColor = fragColor * texture(tex, fragTexCoord);
+}
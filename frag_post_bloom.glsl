@@ -0,0 +1,14 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+//Blurred bright parts of the scene
+uniform sampler2D bloom;
+uniform float intensity;
+
+void main()
+{
+   vec4 color = texture2D(scene, TexCoordOut);
+   color.rgb += texture2D(bloom, TexCoordOut).rgb * intensity;
+   gl_FragColor = color;
+}
\ No newline at end of file
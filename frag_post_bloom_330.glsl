@@ -0,0 +1,16 @@
+#version 330
+
+uniform sampler2D scene;
+//Blurred bright parts of the scene
+uniform sampler2D bloom;
+uniform float intensity;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   vec4 color = texture(scene, fragTexCoord);
+   color.rgb += texture(bloom, fragTexCoord).rgb * intensity;
+   outputColor = color;
+}
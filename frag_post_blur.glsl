@@ -0,0 +1,21 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+uniform vec2 resolution;
+//(1, 0) for the horizontal pass, (0, 1) for the vertical one
+uniform vec2 direction;
+
+void main()
+{
+   //9-tap gaussian, one side of the symmetric kernel
+   float weights[5] = float[5](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+   vec2 step = direction / resolution;
+
+   vec3 color = texture2D(scene, TexCoordOut).rgb * weights[0];
+   for (int i = 1; i < 5; i++) {
+      color += texture2D(scene, TexCoordOut + step * float(i)).rgb * weights[i];
+      color += texture2D(scene, TexCoordOut - step * float(i)).rgb * weights[i];
+   }
+   gl_FragColor = vec4(color, 1.0);
+}
\ No newline at end of file
@@ -0,0 +1,14 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+//Luminance where blooming starts
+uniform float threshold;
+
+void main()
+{
+   vec3 color = texture2D(scene, TexCoordOut).rgb;
+   float luminance = dot(color, vec3(0.2126, 0.7152, 0.0722));
+   float amount = smoothstep(threshold, threshold + 0.2, luminance);
+   gl_FragColor = vec4(color * amount, 1.0);
+}
\ No newline at end of file
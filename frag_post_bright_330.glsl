@@ -0,0 +1,17 @@
+#version 330
+
+uniform sampler2D scene;
+//Luminance where blooming starts
+uniform float threshold;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   vec3 color = texture(scene, fragTexCoord).rgb;
+   float luminance = dot(color, vec3(0.2126, 0.7152, 0.0722));
+   //soft knee so glow fades in rather than popping
+   float amount = smoothstep(threshold, threshold + 0.2, luminance);
+   outputColor = vec4(color * amount, 1.0);
+}
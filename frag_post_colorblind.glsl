@@ -0,0 +1,12 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+//Colour vision deficiency simulation
+uniform mat3 simulation;
+
+void main()
+{
+   vec4 color = texture2D(scene, TexCoordOut);
+   gl_FragColor = vec4(clamp(simulation * color.rgb, 0.0, 1.0), color.a);
+}
\ No newline at end of file
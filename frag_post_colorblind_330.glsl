@@ -0,0 +1,14 @@
+#version 330
+
+uniform sampler2D scene;
+//Colour vision deficiency simulation, applied to linear-ish rgb
+uniform mat3 simulation;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   vec4 color = texture(scene, fragTexCoord);
+   outputColor = vec4(clamp(simulation * color.rgb, 0.0, 1.0), color.a);
+}
@@ -0,0 +1,9 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+
+void main()
+{
+   gl_FragColor = texture2D(scene, TexCoordOut);
+}
\ No newline at end of file
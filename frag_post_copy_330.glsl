@@ -0,0 +1,11 @@
+#version 330
+
+uniform sampler2D scene;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   outputColor = texture(scene, fragTexCoord);
+}
@@ -0,0 +1,30 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+uniform vec2 resolution;
+//Barrel distortion of the glass
+uniform float curvature;
+//Darkness between scanlines, 0 to 1
+uniform float scanlines;
+
+void main()
+{
+   vec2 centered = TexCoordOut * 2.0 - 1.0;
+   centered *= 1.0 + curvature * dot(centered, centered);
+   vec2 uv = centered * 0.5 + 0.5;
+   if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+      gl_FragColor = vec4(0.0, 0.0, 0.0, 1.0);
+      return;
+   }
+
+   vec2 fringe = vec2(1.0 / resolution.x, 0.0);
+   vec3 color;
+   color.r = texture2D(scene, uv + fringe).r;
+   color.g = texture2D(scene, uv).g;
+   color.b = texture2D(scene, uv - fringe).b;
+
+   float line = 0.5 + 0.5 * sin(uv.y * resolution.y * 3.14159);
+   color *= 1.0 - scanlines * (1.0 - line);
+   gl_FragColor = vec4(color, 1.0);
+}
\ No newline at end of file
@@ -0,0 +1,14 @@
+#version 120
+varying vec2 TexCoordOut;
+
+uniform sampler2D scene;
+//0 leaves the corners alone, 1 takes them to black
+uniform float strength;
+
+void main()
+{
+   vec4 color = texture2D(scene, TexCoordOut);
+   float dist = length(TexCoordOut - vec2(0.5)) * 1.4142;
+   color.rgb *= 1.0 - strength * smoothstep(0.4, 1.0, dist);
+   gl_FragColor = color;
+}
\ No newline at end of file
@@ -0,0 +1,16 @@
+#version 330
+
+uniform sampler2D scene;
+//0 leaves the corners alone, 1 takes them to black
+uniform float strength;
+
+in vec2 fragTexCoord;
+
+out vec4 outputColor;
+
+void main() {
+   vec4 color = texture(scene, fragTexCoord);
+   float dist = length(fragTexCoord - vec2(0.5)) * 1.4142;
+   color.rgb *= 1.0 - strength * smoothstep(0.4, 1.0, dist);
+   outputColor = color;
+}
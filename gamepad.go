@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	"math"
+)
+
+// Axis and button numbers follow the usual XInput / Linux xpad layout,
+// other pads can be remapped in the config file
+type GamepadConfig struct {
+	//stick travel ignored around the centre, 0 to 1
+	DeadZone float64 `json:"deadZone"`
+	//paddle movement
+	StickX int `json:"stickX"`
+	//menu navigation, negative is up
+	StickY int `json:"stickY"`
+	//launches the ball, selects menu items
+	Launch int `json:"launch"`
+	//pauses the game, backs out of menus
+	Pause int `json:"pause"`
+}
+
+var DefaultGamepadConfig = GamepadConfig{
+	DeadZone: 0.2,
+	StickX:   0,
+	StickY:   1,
+	Launch:   0,
+	Pause:    7,
+}
+
+// Stick past this far counts as a menu up/down press
+const GamepadMenuThreshold = 0.6
+
+// The first connected joystick, polled once per frame. Buttons and menu
+// stick movements are fed to the state stack as presses of the keys bound
+// to their actions, so everything that handles keys handles the pad too.
+type Gamepad struct {
+	joy       glfw.Joystick
+	connected bool
+	name      string
+	buttons   []byte
+	//key sent for each button being held, released with it
+	held map[int]Binding
+	//last menu direction from the stick, -1 up, 0 centred, 1 down
+	menuDir int
+	stick   float64
+}
+
+var gGamepad Gamepad
+
+// Check for hot-plugged pads and forward input, call after glfw.PollEvents
+func (g *Gamepad) Poll() {
+	if g.connected && !glfw.JoystickPresent(g.joy) {
+		fmt.Println("gamepad disconnected:", g.name)
+		g.release()
+		g.connected = false
+		//don't leave the game running without its controller
+		if play, ok := gStates.Top().(*PlayingState); ok {
+			play.Suspend(MakePauseState(play.world))
+		}
+	}
+	if !g.connected && !g.connect() {
+		return
+	}
+
+	config := &gOptions.Gamepad
+	axes := glfw.GetJoystickAxes(g.joy)
+	g.stick = applyDeadZone(axisValue(axes, config.StickX), config.DeadZone)
+
+	menuDir := 0
+	if y := axisValue(axes, config.StickY); y < -GamepadMenuThreshold {
+		menuDir = -1
+	} else if y > GamepadMenuThreshold {
+		menuDir = 1
+	}
+	if menuDir != g.menuDir {
+		g.menuDir = menuDir
+		if menuDir < 0 {
+			g.tap(ActionMenuUp)
+		} else if menuDir > 0 {
+			g.tap(ActionMenuDown)
+		}
+	}
+
+	buttons := glfw.GetJoystickButtons(g.joy)
+	for _, b := range []struct {
+		index   int
+		actions []InputAction
+	}{
+		{config.Launch, []InputAction{ActionLaunch, ActionSelect}},
+		{config.Pause, []InputAction{ActionPause, ActionBack}},
+	} {
+		was, is := buttonDown(g.buttons, b.index), buttonDown(buttons, b.index)
+		if is && !was {
+			g.press(b.index, b.actions)
+		} else if was && !is {
+			g.releaseButton(b.index)
+		}
+	}
+	g.buttons = append(g.buttons[:0], buttons...)
+}
+
+// Press the key for the first of actions the current state wants
+func (g *Gamepad) press(button int, actions []InputAction) {
+	for _, a := range actions {
+		b, ok := gInput.First(a)
+		if ok && gStates.HandleKey(b.key, 0, glfw.Press, b.mods) {
+			if g.held == nil {
+				g.held = make(map[int]Binding)
+			}
+			g.held[button] = b
+			return
+		}
+	}
+}
+
+func (g *Gamepad) releaseButton(button int) {
+	if b, ok := g.held[button]; ok {
+		delete(g.held, button)
+		gStates.HandleKey(b.key, 0, glfw.Release, b.mods)
+	}
+}
+
+func (g *Gamepad) connect() bool {
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		if glfw.JoystickPresent(joy) {
+			g.joy = joy
+			g.connected = true
+			g.name = glfw.GetJoystickName(joy)
+			g.buttons = g.buttons[:0]
+			g.menuDir = 0
+			fmt.Println("gamepad connected:", g.name)
+			return true
+		}
+	}
+	return false
+}
+
+// Let go of anything held, so a pulled cable doesn't leave keys down
+func (g *Gamepad) release() {
+	for button := range g.held {
+		g.releaseButton(button)
+	}
+	g.buttons = g.buttons[:0]
+	g.stick = 0
+	g.menuDir = 0
+}
+
+func (g *Gamepad) tap(a InputAction) {
+	if b, ok := gInput.First(a); ok {
+		gStates.HandleKey(b.key, 0, glfw.Press, b.mods)
+		gStates.HandleKey(b.key, 0, glfw.Release, b.mods)
+	}
+}
+
+// Horizontal stick position after the dead zone, -1 to 1
+func (g *Gamepad) Stick() float64 {
+	return g.stick
+}
+
+func axisValue(axes []float32, i int) float64 {
+	if i < 0 || i >= len(axes) {
+		return 0
+	}
+	return float64(axes[i])
+}
+
+func buttonDown(buttons []byte, i int) bool {
+	return i >= 0 && i < len(buttons) && buttons[i] == byte(glfw.Press)
+}
+
+// Rescale so movement starts from 0 at the edge of the dead zone rather
+// than jumping to it
+func applyDeadZone(v, deadZone float64) float64 {
+	magnitude := math.Abs(v)
+	if magnitude <= deadZone || deadZone >= 1 {
+		return 0
+	}
+	scaled := math.Min((magnitude-deadZone)/(1-deadZone), 1)
+	return math.Copysign(scaled, v)
+}
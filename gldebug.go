@@ -0,0 +1,11 @@
+//go:build !debug
+// +build !debug
+
+package main
+
+// Release builds leave GL debug output off, build with -tags debug to log
+// driver messages
+const glDebugBuild = false
+
+func enableGLDebugOutput() {
+}
@@ -0,0 +1,37 @@
+//go:build debug
+// +build debug
+
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	"unsafe"
+)
+
+// Ask for a debug context and log what the driver reports through KHR_debug
+const glDebugBuild = true
+
+var glDebugSeverities = map[uint32]string{
+	gl.DEBUG_SEVERITY_HIGH:         "high",
+	gl.DEBUG_SEVERITY_MEDIUM:       "medium",
+	gl.DEBUG_SEVERITY_LOW:          "low",
+	gl.DEBUG_SEVERITY_NOTIFICATION: "note",
+}
+
+func enableGLDebugOutput() {
+	if !glfw.ExtensionSupported("GL_KHR_debug") {
+		fmt.Println("GL debug output unavailable: no GL_KHR_debug")
+		return
+	}
+	gl.Enable(gl.DEBUG_OUTPUT)
+	//report on the thread and call that caused the message
+	gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	gl.DebugMessageCallback(func(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+		if severity == gl.DEBUG_SEVERITY_NOTIFICATION {
+			return
+		}
+		fmt.Printf("GL debug [%s] %d: %s\n", glDebugSeverities[severity], id, message)
+	}, nil)
+}
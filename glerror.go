@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"image"
+	"image/color"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var glErrorNames = map[uint32]string{
+	gl.INVALID_ENUM:                  "GL_INVALID_ENUM",
+	gl.INVALID_VALUE:                 "GL_INVALID_VALUE",
+	gl.INVALID_OPERATION:             "GL_INVALID_OPERATION",
+	gl.STACK_OVERFLOW:                "GL_STACK_OVERFLOW",
+	gl.STACK_UNDERFLOW:               "GL_STACK_UNDERFLOW",
+	gl.OUT_OF_MEMORY:                 "GL_OUT_OF_MEMORY",
+	gl.INVALID_FRAMEBUFFER_OPERATION: "GL_INVALID_FRAMEBUFFER_OPERATION",
+}
+
+func glErrorName(code uint32) string {
+	if name, ok := glErrorNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("GL error 0x%x", code)
+}
+
+// Errors raised by GL since the last check, with where they were noticed
+type GLError struct {
+	codes []uint32
+	//what was being done, e.g. "upload texture"
+	op string
+	//file:line of the checkGLerror call
+	site string
+}
+
+func (e *GLError) Error() string {
+	names := make([]string, len(e.codes))
+	for i, c := range e.codes {
+		names[i] = glErrorName(c)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.op, strings.Join(names, ", "), e.site)
+}
+
+// Drain the GL error queue, nil if it was empty. GL only records the first
+// error of each kind, so everything since the previous check is reported.
+func checkGLerror(op string) error {
+	var codes []uint32
+	for glerr := gl.GetError(); glerr != gl.NO_ERROR; glerr = gl.GetError() {
+		codes = append(codes, glerr)
+		//a lost context reports forever
+		if len(codes) > len(glErrorNames) {
+			break
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	site := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		site = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return &GLError{codes, op, site}
+}
+
+// Magenta and black checkerboard, drawn in place of images that failed to
+// load so the problem is obvious without stopping the game
+func makePlaceholderImage(width, height int) *image.RGBA {
+	if width <= 0 || height <= 0 {
+		width, height = 32, 32
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	magenta := color.RGBA{255, 0, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x/8+y/8)%2 == 0 {
+				img.SetRGBA(x, y, magenta)
+			} else {
+				img.SetRGBA(x, y, black)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+)
+
+// What the current context has beyond GL 2.1, set by loadGL. Nothing
+// newer than 2.1 is called unless this says it is there.
+var gGLCaps struct {
+	//vertex array objects, which core profiles can't draw without
+	vertexArrays bool
+	//offscreen render targets for post-processing, see RenderTarget
+	framebuffers bool
+}
+
+// Load the GL entry points and work out what the context supports. The
+// binding is 3.3 core, so a 4.1 context is driven as 3.3 and a 3.3 driver
+// has every function it asks for. A 2.1 context needs a driver that still
+// exports the 3.x names, as Mesa and macOS do, but only the ones gGLCaps
+// allows are ever called.
+func loadGL() error {
+	if err := gl.Init(); err != nil {
+		return err
+	}
+	window := glfw.GetCurrentContext()
+	major := window.GetAttrib(glfw.ContextVersionMajor)
+	gGLCaps.vertexArrays = gGLProfile.core
+	//core in 3.0, and ARB_framebuffer_object brings the same names to 2.1.
+	//EXT_framebuffer_object has its own names, which aren't bound.
+	gGLCaps.framebuffers = major >= 3 || glfw.ExtensionSupported("GL_ARB_framebuffer_object")
+	return nil
+}
+
+// Compatibility contexts draw without a vertex array object, using the
+// default attribute state that every draw sets up again anyway
+func genVertexArray() uint32 {
+	var vao uint32
+	if gGLCaps.vertexArrays {
+		gl.GenVertexArrays(1, &vao)
+	}
+	return vao
+}
+
+func bindVertexArray(vao uint32) {
+	if gGLCaps.vertexArrays {
+		gl.BindVertexArray(vao)
+	}
+}
@@ -0,0 +1,247 @@
+// Training environment over the headless simulation, in the style of
+// OpenAI Gym: Reset starts an episode, Step plays an action and says what
+// came of it. Episodes are single player games.
+package gym
+
+import (
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"math/rand"
+)
+
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionLeft
+	ActionRight
+	// launch the ball if it's waiting on the paddle, otherwise stay put
+	ActionLaunch
+)
+
+const NumActions = 4
+
+const (
+	RewardBlock    = 1
+	RewardBallLost = -1
+)
+
+// Block occupancy grid in the state vector, over the whole stage
+const (
+	GridCols = 16
+	GridRows = 16
+)
+
+// Length of Observation.State
+const StateSize = 8 + GridCols*GridRows
+
+type Config struct {
+	//which observations to make
+	State  bool
+	Pixels bool
+	//size of pixel observations
+	FrameWidth  int
+	FrameHeight int
+	//ticks each action is held for, at least 1
+	Repeat int
+	//steps before an episode is cut short, 0 for no limit
+	MaxSteps int
+	//levels generated from the episode's seed, instead of the classic
+	//layout every time
+	Generated bool
+}
+
+var DefaultConfig = Config{
+	State:       true,
+	FrameWidth:  84,
+	FrameHeight: 112,
+	Repeat:      4,
+	MaxSteps:    10000,
+}
+
+// What the agent sees after Reset and each Step
+type Observation struct {
+	//see StateSize for the layout
+	State []float32 `json:"state,omitempty"`
+	//FrameWidth*FrameHeight RGB pixels, rows top to bottom, see Render
+	Pixels []byte `json:"pixels,omitempty"`
+}
+
+type Env struct {
+	config Config
+	world  *sim.World
+	input  *sim.InputController
+	rand   *rand.Rand
+	steps  int
+	done   bool
+}
+
+func MakeEnv(config Config) (*Env, error) {
+	if config.Pixels && (config.FrameWidth <= 0 || config.FrameHeight <= 0) {
+		return nil, fmt.Errorf("frame size %dx%d, expected positive", config.FrameWidth, config.FrameHeight)
+	}
+	if config.Repeat < 1 {
+		config.Repeat = 1
+	}
+	e := &Env{config: config}
+	e.Reset(0)
+	return e, nil
+}
+
+// Start a new episode. The seed picks where the paddle starts, which way
+// each serve goes and any generated levels; the same seed and actions
+// always play out the same.
+func (e *Env) Reset(seed int64) Observation {
+	e.rand = rand.New(rand.NewSource(seed))
+	e.world = sim.MakeWorld(sim.DefaultStageSize, sim.ModeSingle)
+	if e.config.Generated {
+		e.world.SetLevels(sim.GeneratedLevels{Seed: seed})
+	}
+	e.input = &sim.InputController{}
+	paddle := e.world.Players[0].Paddle
+	paddle.SetController(e.input)
+	paddle.Nudge(e.rand.Float64()*sim.DefaultStageSize[0], sim.DefaultStageSize)
+	//the first tick would put it there, the first observation should too
+	e.world.Players[0].Ball.Serve(paddle)
+	e.steps = 0
+	e.done = false
+	return e.observe()
+}
+
+// Play a for Config.Repeat ticks. Returns the reward earned meanwhile and
+// whether the episode is over, after which only Reset does anything.
+func (e *Env) Step(a Action) (Observation, float64, bool) {
+	if e.done {
+		return e.observe(), 0, true
+	}
+	player := e.world.Players[0]
+	switch a {
+	case ActionLeft:
+		e.input.Input = sim.MakeTickInput(-1, false)
+	case ActionRight:
+		e.input.Input = sim.MakeTickInput(1, false)
+	default:
+		e.input.Input = sim.TickInput{}
+	}
+	if a == ActionLaunch && player.Serving {
+		e.world.Launch(0)
+		if e.rand.Intn(2) == 0 {
+			player.Ball.Velocity[0] = -player.Ball.Velocity[0]
+		}
+	}
+
+	var reward float64
+	for i := 0; i < e.config.Repeat && !e.done; i++ {
+		status := e.world.Update()
+		for _, ev := range e.world.TakeEvents() {
+			switch ev.Kind {
+			case sim.EventBlockDestroyed:
+				reward += RewardBlock
+			case sim.EventBallLost:
+				reward += RewardBallLost
+			}
+		}
+		switch status {
+		case sim.WorldLevelComplete:
+			e.world.NextLevel()
+		case sim.WorldGameOver:
+			e.done = true
+		}
+	}
+	e.steps++
+	if e.config.MaxSteps > 0 && e.steps >= e.config.MaxSteps {
+		e.done = true
+	}
+	return e.observe(), reward, e.done
+}
+
+// The game being played, to look at rather than change
+func (e *Env) World() *sim.World {
+	return e.world
+}
+
+func (e *Env) observe() Observation {
+	var o Observation
+	if e.config.State {
+		o.State = e.state()
+	}
+	if e.config.Pixels {
+		o.Pixels = Render(e.world, e.config.FrameWidth, e.config.FrameHeight)
+	}
+	return o
+}
+
+// Positions are fractions of the stage, so every value is -1 to 1:
+//
+//	0 paddle centre x
+//	1 ball centre x
+//	2 ball centre y
+//	3 ball velocity x
+//	4 ball velocity y
+//	5 ball x relative to the paddle, the short way round the cylinder
+//	6 1 while the ball waits on the paddle, else 0
+//	7 lives left, out of sim.StartingLives
+//	8 on, GridRows rows top to bottom of GridCols cells: hits left on the
+//	  block over the cell's centre out of sim.MaxBlockHP, 0 for none and -1
+//	  for a solid block
+func (e *Env) state() []float32 {
+	w := e.world
+	player := w.Players[0]
+	paddle := player.Paddle.Center()
+	ball := player.Ball.Center()
+	serving := 0
+	if player.Serving {
+		serving = 1
+	}
+	s := make([]float32, StateSize)
+	copy(s, []float32{
+		e.across(paddle[0]),
+		e.across(ball[0]),
+		float32(ball[1] / w.StageSize[1]),
+		float32(player.Ball.Velocity[0]),
+		float32(player.Ball.Velocity[1]),
+		float32(w.WrapDelta(paddle[0], ball[0]) / w.StageSize[0]),
+		float32(serving),
+		float32(player.Lives) / sim.StartingLives,
+	})
+	cell := mgl.Vec2{w.StageSize[0] / GridCols, w.StageSize[1] / GridRows}
+	for _, b := range w.Blocks {
+		//every cell whose centre the block covers
+		for row := 0; row < GridRows; row++ {
+			y := w.StageSize[1] - (float64(row)+0.5)*cell[1]
+			if y < b.Pos[1] || y >= b.Pos[1]+b.Size[1] {
+				continue
+			}
+			for col := 0; col < GridCols; col++ {
+				//or that its part hanging past the seam covers
+				x := (float64(col) + 0.5) * cell[0]
+				if !covers(b, x) && !covers(b, x+w.StageSize[0]) {
+					continue
+				}
+				if b.Solid {
+					s[8+row*GridCols+col] = -1
+				} else {
+					s[8+row*GridCols+col] = float32(b.HP) / sim.MaxBlockHP
+				}
+			}
+		}
+	}
+	return s
+}
+
+// x as a fraction of the stage width, wrapped onto the cylinder. Centres
+// are half a width past Pos, so can be over the seam.
+func (e *Env) across(x float64) float32 {
+	width := e.world.StageSize[0]
+	x = math.Mod(x, width)
+	if x < 0 {
+		x += width
+	}
+	return float32(x / width)
+}
+
+func covers(b *sim.Block, x float64) bool {
+	return x >= b.Pos[0] && x < b.Pos[0]+b.Size[0]
+}
@@ -0,0 +1,215 @@
+package gym
+
+import (
+	"bytes"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math/rand"
+	"testing"
+)
+
+func makeEnv(t *testing.T, config Config) *Env {
+	t.Helper()
+	e, err := MakeEnv(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// Launch, then put the ball's centre at pos heading along velocity
+func aim(e *Env, pos, velocity mgl.Vec2) {
+	e.Step(ActionLaunch)
+	ball := e.world.Players[0].Ball
+	ball.Pos = pos.Sub(ball.Size.Mul(0.5))
+	ball.Velocity = velocity.Normalize()
+}
+
+func TestSameSeedSamePlay(t *testing.T) {
+	config := DefaultConfig
+	config.Pixels = true
+	config.Generated = true
+	a, b := makeEnv(t, config), makeEnv(t, config)
+	oa, ob := a.Reset(42), b.Reset(42)
+	actions := rand.New(rand.NewSource(1))
+	for step := 0; step < 2000; step++ {
+		if !equalObservations(oa, ob) {
+			t.Fatalf("step %d: observations differ", step)
+		}
+		action := Action(actions.Intn(NumActions))
+		var ra, rb float64
+		var da, db bool
+		oa, ra, da = a.Step(action)
+		ob, rb, db = b.Step(action)
+		if ra != rb || da != db {
+			t.Fatalf("step %d: rewards %v and %v, done %v and %v", step, ra, rb, da, db)
+		}
+		if da {
+			break
+		}
+	}
+
+	//and a different seed plays a different game
+	if equalObservations(a.Reset(42), b.Reset(43)) {
+		t.Error("seeds 42 and 43 start the same")
+	}
+}
+
+func equalObservations(a, b Observation) bool {
+	if len(a.State) != len(b.State) || !bytes.Equal(a.Pixels, b.Pixels) {
+		return false
+	}
+	for i := range a.State {
+		if a.State[i] != b.State[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRewardForBlock(t *testing.T) {
+	e := makeEnv(t, DefaultConfig)
+	//a one-hit block in the bottom row, with nothing below it
+	var target *sim.Block
+	for _, b := range e.world.Blocks {
+		if !b.Solid && b.HP == 1 && (target == nil || b.Pos[1] < target.Pos[1]) {
+			target = b
+		}
+	}
+	if target == nil {
+		t.Fatal("no one-hit block in the first level")
+	}
+	aim(e, target.Center().Sub(mgl.Vec2{0, target.Size[1]}), mgl.Vec2{0, 1})
+	blocks := len(e.world.Blocks)
+	for step := 0; step < 10; step++ {
+		_, reward, _ := e.Step(ActionNone)
+		if reward != 0 {
+			if reward != RewardBlock {
+				t.Errorf("reward %v for breaking a block, want %v", reward, RewardBlock)
+			}
+			if len(e.world.Blocks) != blocks-1 {
+				t.Errorf("%d blocks left of %d", len(e.world.Blocks), blocks)
+			}
+			return
+		}
+	}
+	t.Fatal("ball never broke the block")
+}
+
+func TestRewardForLostBall(t *testing.T) {
+	e := makeEnv(t, DefaultConfig)
+	paddle := e.world.Players[0].Paddle.Center()
+	//on the far side of the stage from the paddle, touching the floor
+	aim(e, mgl.Vec2{paddle[0] + e.world.StageSize[0]/2, 0}, mgl.Vec2{0, -1})
+	lives := e.world.Players[0].Lives
+	_, reward, done := e.Step(ActionNone)
+	if reward != RewardBallLost {
+		t.Errorf("reward %v for losing the ball, want %v", reward, RewardBallLost)
+	}
+	if done || e.world.Players[0].Lives != lives-1 {
+		t.Errorf("done %v with %d lives, want a life gone from %d", done, e.world.Players[0].Lives, lives)
+	}
+}
+
+func TestDoneAtGameOver(t *testing.T) {
+	e := makeEnv(t, DefaultConfig)
+	done := false
+	for i := 0; i < sim.StartingLives && !done; i++ {
+		paddle := e.world.Players[0].Paddle.Center()
+		aim(e, mgl.Vec2{paddle[0] + e.world.StageSize[0]/2, 0}, mgl.Vec2{0, -1})
+		_, _, done = e.Step(ActionNone)
+	}
+	if !done {
+		t.Fatalf("not done after losing %d balls", sim.StartingLives)
+	}
+	if _, reward, done := e.Step(ActionLaunch); !done || reward != 0 {
+		t.Errorf("stepping a finished episode gave reward %v, done %v", reward, done)
+	}
+	o := e.Reset(1)
+	if _, _, done := e.Step(ActionNone); done || o.State[7] != 1 {
+		t.Error("Reset didn't start a new episode")
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	config := DefaultConfig
+	config.MaxSteps = 5
+	e := makeEnv(t, config)
+	for step := 1; step <= 5; step++ {
+		if _, _, done := e.Step(ActionNone); done != (step == 5) {
+			t.Fatalf("step %d: done %v", step, done)
+		}
+	}
+}
+
+func TestStateInRange(t *testing.T) {
+	config := DefaultConfig
+	config.Generated = true
+	config.MaxSteps = 0
+	e := makeEnv(t, config)
+	actions := rand.New(rand.NewSource(2))
+	for seed := int64(0); seed < 5; seed++ {
+		o := e.Reset(seed)
+		for step := 0; step < 5000; step++ {
+			if len(o.State) != StateSize {
+				t.Fatalf("state has %d values, want %d", len(o.State), StateSize)
+			}
+			for i, v := range o.State {
+				if v < -1 || v > 1 {
+					t.Fatalf("seed %d step %d: state[%d] is %v", seed, step, i, v)
+				}
+			}
+			var done bool
+			o, _, done = e.Step(Action(actions.Intn(NumActions)))
+			if done {
+				o = e.Reset(seed + 100)
+			}
+		}
+	}
+}
+
+func TestGridWrapsAtSeam(t *testing.T) {
+	e := makeEnv(t, DefaultConfig)
+	w := e.world
+	cell := w.StageSize[0] / GridCols
+	//one block hanging a cell and a half over the right edge, top row
+	block := sim.MakeBlock(mgl.Vec2{2 * cell, w.StageSize[1] / GridRows}, mgl.Vec2{w.StageSize[0] - cell/2, w.StageSize[1] - w.StageSize[1]/GridRows}, mgl.Vec3{1, 1, 1}, 1)
+	w.Blocks = []*sim.Block{block}
+	s := e.state()
+	for col := 0; col < GridCols; col++ {
+		want := float32(0)
+		if col == 0 || col == GridCols-1 {
+			want = 1.0 / sim.MaxBlockHP
+		}
+		if got := s[8+col]; got != want {
+			t.Errorf("top row cell %d is %v, want %v", col, got, want)
+		}
+	}
+}
+
+func TestRenderSize(t *testing.T) {
+	e := makeEnv(t, DefaultConfig)
+	for _, size := range [][2]int{{84, 112}, {1, 1}, {200, 30}, {7, 300}} {
+		if got := len(Render(e.world, size[0], size[1])); got != size[0]*size[1]*3 {
+			t.Errorf("%dx%d frame is %d bytes, want %d", size[0], size[1], got, size[0]*size[1]*3)
+		}
+	}
+
+	config := DefaultConfig
+	config.State = false
+	config.Pixels = true
+	o := makeEnv(t, config).Reset(0)
+	if len(o.Pixels) != config.FrameWidth*config.FrameHeight*3 || o.State != nil {
+		t.Errorf("observation has %d pixel bytes and %d state values", len(o.Pixels), len(o.State))
+	}
+	//the background shows in the corner under the blocks
+	bottom := o.Pixels[len(o.Pixels)-3:]
+	if want := []byte{toByte(BackgroundColor[0]), toByte(BackgroundColor[1]), toByte(BackgroundColor[2])}; !bytes.Equal(bottom, want) {
+		t.Errorf("bottom right pixel %v, want the background %v", bottom, want)
+	}
+
+	config.FrameWidth = 0
+	if _, err := MakeEnv(config); err == nil {
+		t.Error("zero frame width accepted")
+	}
+}
@@ -0,0 +1,75 @@
+package gym
+
+import (
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+var (
+	BackgroundColor = mgl.Vec4{0.05, 0.05, 0.1, 1}
+	PaddleColor     = mgl.Vec4{1, 1, 1, 1}
+	BallColor       = mgl.Vec4{1, 1, 1, 1}
+)
+
+// Draw the stage unrolled flat, top at the top, as width*height RGB bytes.
+// No GL, so it runs anywhere the simulation does.
+func Render(w *sim.World, width, height int) []byte {
+	f := frame{make([]byte, width*height*3), width, height, w.StageSize}
+	f.fill(mgl.Vec2{0, 0}, w.StageSize, BackgroundColor)
+	for _, b := range w.Blocks {
+		f.fill(b.Pos, b.Size, b.Tint())
+	}
+	for _, p := range w.Players {
+		f.fill(p.Paddle.Pos, p.Paddle.Size, PaddleColor)
+		if !p.Out() {
+			f.fill(p.Ball.Pos, p.Ball.Size, BallColor)
+		}
+	}
+	return f.pixels
+}
+
+type frame struct {
+	pixels    []byte
+	width     int
+	height    int
+	stageSize mgl.Vec2
+}
+
+// Fill the pixels whose centres are inside a rectangle of the stage, and
+// its copies either side, so things crossing the seam show on both edges
+func (f *frame) fill(pos, size mgl.Vec2, color mgl.Vec4) {
+	for _, shift := range []float64{-f.stageSize[0], 0, f.stageSize[0]} {
+		f.fillOnce(mgl.Vec2{pos[0] + shift, pos[1]}, size, color)
+	}
+}
+
+func (f *frame) fillOnce(pos, size mgl.Vec2, color mgl.Vec4) {
+	sx := float64(f.width) / f.stageSize[0]
+	sy := float64(f.height) / f.stageSize[1]
+	x0 := clamp(int(math.Ceil(pos[0]*sx-0.5)), 0, f.width)
+	x1 := clamp(int(math.Ceil((pos[0]+size[0])*sx-0.5)), 0, f.width)
+	//stage y goes up, rows go down
+	y0 := clamp(int(math.Ceil((f.stageSize[1]-pos[1]-size[1])*sy-0.5)), 0, f.height)
+	y1 := clamp(int(math.Ceil((f.stageSize[1]-pos[1])*sy-0.5)), 0, f.height)
+	rgb := [3]byte{toByte(color[0]), toByte(color[1]), toByte(color[2])}
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			copy(f.pixels[(y*f.width+x)*3:], rgb[:])
+		}
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func toByte(c float64) byte {
+	return byte(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
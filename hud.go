@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl32 "github.com/go-gl/mathgl/mgl32"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"strings"
+	"time"
+)
+
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// Margin between anchored HUD elements and the screen edge, in screen units
+const HUDMargin = 12
+
+// Top left corner of a box of the given size placed at anchor on a screen
+// of screenSize, keeping margin away from the edges it is anchored to
+func AnchorBox(anchor Anchor, size, screenSize mgl.Vec2, margin float64) mgl.Vec2 {
+	var pos mgl.Vec2
+	switch anchor % 3 {
+	case 0:
+		pos[0] = margin
+	case 1:
+		pos[0] = (screenSize[0] - size[0]) / 2
+	case 2:
+		pos[0] = screenSize[0] - size[0] - margin
+	}
+	switch anchor / 3 {
+	case 0:
+		pos[1] = margin
+	case 1:
+		pos[1] = (screenSize[1] - size[1]) / 2
+	case 2:
+		pos[1] = screenSize[1] - size[1] - margin
+	}
+	return pos
+}
+
+// Frames per second, averaged over half a second so the number is readable
+type FPSCounter struct {
+	frames  int
+	elapsed time.Duration
+	fps     float64
+}
+
+func (f *FPSCounter) Tick(elapsed time.Duration) {
+	f.frames++
+	f.elapsed += elapsed
+	if f.elapsed >= time.Second/2 {
+		f.fps = float64(f.frames) / f.elapsed.Seconds()
+		f.frames = 0
+		f.elapsed = 0
+	}
+}
+
+func (f *FPSCounter) FPS() float64 {
+	return f.fps
+}
+
+var (
+	HUDWhite  = mgl.Vec4{1, 1, 1, 1}
+	HUDShadow = mgl.Vec4{0, 0, 0, 0.6}
+	HUDDim    = mgl.Vec4{0, 0, 0, 0.5}
+)
+
+type HUD struct {
+	text  *TextRenderer
+	panel RenderComponent
+	fps   FPSCounter
+}
+
+var gHUD *HUD
+
+func MakeHUD() (*HUD, error) {
+	program, err := GetHUDShaderProgram()
+	if err != nil {
+		return nil, err
+	}
+	white, err := createWhiteTexture()
+	if err != nil {
+		return nil, err
+	}
+	font, err := MakeBitmapFont()
+	if err != nil {
+		return nil, err
+	}
+	text, err := MakeTextRenderer(font, 3)
+	if err != nil {
+		return nil, err
+	}
+	vao, vbo, indexBuffer := makeDynamicVertexArrayObject()
+	panel := MakeRenderComponent(vao, vbo, indexBuffer, 0, white, program)
+	return &HUD{text, panel, FPSCounter{}}, nil
+}
+
+// Screen space is measured in window units, not framebuffer pixels, so
+// text keeps the same apparent size on HiDPI displays
+func (h *HUD) ScreenSize() mgl.Vec2 {
+	return mgl.Vec2{float64(gScreen.windowWidth), float64(gScreen.windowHeight)}
+}
+
+// Orthographic overlay with the origin at the top left, y down
+func (h *HUD) Projection() mgl32.Mat4 {
+	size := h.ScreenSize()
+	return mgl32.Ortho2D(0, float32(size[0]), float32(size[1]), 0)
+}
+
+// Wrap HUD drawing so it ignores the depth of the world below
+func (h *HUD) Begin() {
+	BeginOverlay()
+}
+
+func (h *HUD) End() {
+	EndOverlay()
+}
+
+// Draw s with its top left corner at pos, with a drop shadow
+func (h *HUD) LabelAt(s string, pos mgl.Vec2, tint mgl.Vec4) {
+	P := h.Projection()
+	shadowOffset := mgl.Vec2{h.text.scale, h.text.scale}
+	h.text.Draw(s, pos.Add(shadowOffset), HUDShadow, P)
+	h.text.Draw(s, pos, tint, P)
+}
+
+// Draw s anchored to a screen edge or corner
+func (h *HUD) Label(s string, anchor Anchor, tint mgl.Vec4) {
+	pos := AnchorBox(anchor, h.text.Measure(s), h.ScreenSize(), HUDMargin)
+	h.LabelAt(s, pos, tint)
+}
+
+// Solid rectangle in screen units
+func (h *HUD) Rect(pos, size mgl.Vec2, tint mgl.Vec4) {
+	vertices, indices := VertexifyRect(size, 0)
+	updateVertexArrayObject(h.panel.vao, h.panel.vbo, h.panel.indexBuffer, vertices, indices)
+	h.panel.numIndices = int32(len(indices))
+	h.panel.SetTint(tint)
+	h.panel.Draw(pos, h.Projection())
+}
+
+// Darken whatever is behind a menu
+func (h *HUD) Dim() {
+	h.Rect(mgl.Vec2{0, 0}, h.ScreenSize(), HUDDim)
+}
+
+func (h *HUD) DrawStats(w *sim.World) {
+	h.Begin()
+	defer h.End()
+
+	if len(w.Players) == 1 {
+		p := w.Players[0]
+		h.Label(fmt.Sprintf("SCORE %d", p.Score), AnchorTopLeft, HUDWhite)
+		h.Label(fmt.Sprintf("LIVES %d", p.Lives), AnchorTop, HUDWhite)
+		h.Label(LevelLabel(w), AnchorTopRight, HUDWhite)
+		if p.Serving {
+			launch := strings.ToUpper(gInput.Describe(ActionLaunch))
+			h.Label("PRESS "+launch+" TO LAUNCH", AnchorCenter, HUDWhite)
+		}
+		return
+	}
+
+	//one corner per player, in their paddle colour
+	anchors := []Anchor{AnchorTopLeft, AnchorTopRight}
+	launches := []InputAction{ActionLaunch, ActionP2Launch}
+	var waiting []string
+	for i, p := range w.Players {
+		h.Label(fmt.Sprintf("P%d %d  LIVES %d", i+1, p.Score, p.Lives), anchors[i], PlayerColors[i])
+		if p.Serving && !p.Out() {
+			launch := launches[i]
+			if gNet != nil || gServer != nil {
+				//everyone launches with their own first player keys
+				launch = ActionLaunch
+			}
+			waiting = append(waiting, fmt.Sprintf("P%d %s", i+1, strings.ToUpper(gInput.Describe(launch))))
+		}
+	}
+	h.Label(LevelLabel(w), AnchorTop, HUDWhite)
+	if len(waiting) > 0 {
+		h.Label("LAUNCH: "+strings.Join(waiting, "  "), AnchorCenter, HUDWhite)
+	}
+}
+
+// How far the game has got: the level, or the rows so far under pressure
+func LevelLabel(w *sim.World) string {
+	if w.Pressure != nil {
+		return fmt.Sprintf("ROWS %d", w.PressureRows())
+	}
+	return fmt.Sprintf("LEVEL %d", w.Level)
+}
+
+// Title in the middle of a dimmed screen, e.g. between server games
+func (h *HUD) DrawNotice(title, message string) {
+	h.Begin()
+	defer h.End()
+	h.Dim()
+	h.Label(title, AnchorCenter, HUDWhite)
+	h.Label(message, AnchorBottom, HUDWhite)
+}
+
+// Call once per frame, whether or not the counter is shown
+func (h *HUD) DrawFPS(elapsed time.Duration) {
+	h.fps.Tick(elapsed)
+	if !gOptions.ShowFPS {
+		return
+	}
+
+	h.Begin()
+	defer h.End()
+	h.Label(fmt.Sprintf("%.0f FPS", h.fps.FPS()), AnchorBottomLeft, HUDWhite)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	"strings"
+)
+
+// Something the player can do, bound to one or more keys
+type InputAction int
+
+const (
+	ActionMoveLeft InputAction = iota
+	ActionMoveRight
+	ActionLaunch
+	ActionP2MoveLeft
+	ActionP2MoveRight
+	ActionP2Launch
+	ActionPause
+	ActionMenuUp
+	ActionMenuDown
+	ActionSelect
+	ActionBack
+	ActionCameraLeft
+	ActionCameraRight
+	ActionCameraUp
+	ActionCameraDown
+	ActionCameraIn
+	ActionCameraOut
+	ActionFullscreen
+	ActionEditTest
+	ActionEditSave
+	ActionEditMenu
+	NumInputActions
+)
+
+// Where an action is listened for. Keys may be shared between actions in
+// different contexts, e.g. Escape pauses the game and backs out of menus.
+type InputContext int
+
+const (
+	ContextGlobal InputContext = iota
+	ContextPlay
+	ContextMenu
+	ContextEditor
+)
+
+type InputActionInfo struct {
+	//config file key
+	name string
+	//options menu label
+	label   string
+	context InputContext
+	//default bindings, in config file form
+	defaults []string
+}
+
+var InputActions = [NumInputActions]InputActionInfo{
+	ActionMoveLeft:    {"moveLeft", "MOVE LEFT", ContextPlay, []string{"Left"}},
+	ActionMoveRight:   {"moveRight", "MOVE RIGHT", ContextPlay, []string{"Right"}},
+	ActionLaunch:      {"launch", "LAUNCH", ContextPlay, []string{"Space"}},
+	ActionP2MoveLeft:  {"p2MoveLeft", "P2 MOVE LEFT", ContextPlay, []string{"A"}},
+	ActionP2MoveRight: {"p2MoveRight", "P2 MOVE RIGHT", ContextPlay, []string{"D"}},
+	ActionP2Launch:    {"p2Launch", "P2 LAUNCH", ContextPlay, []string{"W"}},
+	ActionPause:       {"pause", "PAUSE", ContextPlay, []string{"P", "Escape"}},
+	ActionMenuUp:      {"menuUp", "MENU UP", ContextMenu, []string{"Up", "W"}},
+	ActionMenuDown:    {"menuDown", "MENU DOWN", ContextMenu, []string{"Down", "S"}},
+	ActionSelect:      {"select", "SELECT", ContextMenu, []string{"Enter", "Space"}},
+	ActionBack:        {"back", "BACK", ContextMenu, []string{"Escape"}},
+	ActionCameraLeft:  {"cameraLeft", "CAMERA LEFT", ContextPlay, []string{"J"}},
+	ActionCameraRight: {"cameraRight", "CAMERA RIGHT", ContextPlay, []string{"L"}},
+	ActionCameraUp:    {"cameraUp", "CAMERA UP", ContextPlay, []string{"I"}},
+	ActionCameraDown:  {"cameraDown", "CAMERA DOWN", ContextPlay, []string{"K"}},
+	ActionCameraIn:    {"cameraIn", "CAMERA IN", ContextPlay, []string{"O"}},
+	ActionCameraOut:   {"cameraOut", "CAMERA OUT", ContextPlay, []string{"U"}},
+	ActionFullscreen:  {"fullscreen", "FULLSCREEN", ContextGlobal, []string{"F11", "Alt+Enter"}},
+	ActionEditTest:    {"editTest", "EDITOR TEST PLAY", ContextEditor, []string{"T"}},
+	ActionEditSave:    {"editSave", "EDITOR SAVE", ContextEditor, []string{"Ctrl+S"}},
+	ActionEditMenu:    {"editMenu", "EDITOR LEVEL MENU", ContextEditor, []string{"Tab"}},
+}
+
+func (a InputAction) Info() *InputActionInfo {
+	return &InputActions[a]
+}
+
+// The actions that steer and launch for each player
+type PlayerActions struct {
+	left   InputAction
+	right  InputAction
+	launch InputAction
+}
+
+var PlayerInputActions = []PlayerActions{
+	{ActionMoveLeft, ActionMoveRight, ActionLaunch},
+	{ActionP2MoveLeft, ActionP2MoveRight, ActionP2Launch},
+}
+
+// A key, plus modifiers that have to be held with it
+type Binding struct {
+	key  glfw.Key
+	mods glfw.ModifierKey
+}
+
+// The binding's modifiers have to be held, and no others except shift, so
+// holding shift doesn't stop the paddle moving but Alt+Enter isn't Enter
+func (b Binding) Matches(key glfw.Key, mods glfw.ModifierKey) bool {
+	return b.key == key && mods&b.mods == b.mods && (mods&^b.mods)&^glfw.ModShift == 0
+}
+
+var modifierNames = []struct {
+	mod  glfw.ModifierKey
+	name string
+}{
+	{glfw.ModControl, "Ctrl"},
+	{glfw.ModAlt, "Alt"},
+	{glfw.ModShift, "Shift"},
+	{glfw.ModSuper, "Super"},
+}
+
+var keyNames = map[glfw.Key]string{
+	glfw.KeySpace:        "Space",
+	glfw.KeyApostrophe:   "Apostrophe",
+	glfw.KeyComma:        "Comma",
+	glfw.KeyMinus:        "Minus",
+	glfw.KeyPeriod:       "Period",
+	glfw.KeySlash:        "Slash",
+	glfw.KeySemicolon:    "Semicolon",
+	glfw.KeyEqual:        "Equal",
+	glfw.KeyLeftBracket:  "LeftBracket",
+	glfw.KeyBackslash:    "Backslash",
+	glfw.KeyRightBracket: "RightBracket",
+	glfw.KeyGraveAccent:  "Grave",
+	glfw.KeyEscape:       "Escape",
+	glfw.KeyEnter:        "Enter",
+	glfw.KeyTab:          "Tab",
+	glfw.KeyBackspace:    "Backspace",
+	glfw.KeyInsert:       "Insert",
+	glfw.KeyDelete:       "Delete",
+	glfw.KeyRight:        "Right",
+	glfw.KeyLeft:         "Left",
+	glfw.KeyDown:         "Down",
+	glfw.KeyUp:           "Up",
+	glfw.KeyPageUp:       "PageUp",
+	glfw.KeyPageDown:     "PageDown",
+	glfw.KeyHome:         "Home",
+	glfw.KeyEnd:          "End",
+	glfw.KeyLeftShift:    "LeftShift",
+	glfw.KeyLeftControl:  "LeftCtrl",
+	glfw.KeyLeftAlt:      "LeftAlt",
+	glfw.KeyRightShift:   "RightShift",
+	glfw.KeyRightControl: "RightCtrl",
+	glfw.KeyRightAlt:     "RightAlt",
+}
+
+var keysByName = map[string]glfw.Key{}
+
+func init() {
+	for k := glfw.KeyA; k <= glfw.KeyZ; k++ {
+		keyNames[k] = string(rune('A' + k - glfw.KeyA))
+	}
+	for k := glfw.Key0; k <= glfw.Key9; k++ {
+		keyNames[k] = string(rune('0' + k - glfw.Key0))
+	}
+	for k := glfw.KeyF1; k <= glfw.KeyF12; k++ {
+		keyNames[k] = fmt.Sprintf("F%d", k-glfw.KeyF1+1)
+	}
+	for k, name := range keyNames {
+		keysByName[strings.ToLower(name)] = k
+	}
+}
+
+func (b Binding) String() string {
+	var parts []string
+	for _, m := range modifierNames {
+		if b.mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	name, ok := keyNames[b.key]
+	if !ok {
+		name = fmt.Sprintf("Key%d", b.key)
+	}
+	return strings.Join(append(parts, name), "+")
+}
+
+// "Alt+Enter" style, case insensitive
+func ParseBinding(s string) (Binding, error) {
+	var b Binding
+	parts := strings.Split(s, "+")
+	for _, part := range parts[:len(parts)-1] {
+		found := false
+		for _, m := range modifierNames {
+			if strings.EqualFold(part, m.name) {
+				b.mods |= m.mod
+				found = true
+			}
+		}
+		if !found {
+			return b, fmt.Errorf("binding %q: unknown modifier %q", s, part)
+		}
+	}
+	key, ok := keysByName[strings.ToLower(parts[len(parts)-1])]
+	if !ok {
+		return b, fmt.Errorf("binding %q: unknown key", s)
+	}
+	b.key = key
+	return b, nil
+}
+
+// Keys for every action, built from Options.Bindings
+type InputMap [NumInputActions][]Binding
+
+var gInput InputMap
+
+func DefaultBindings() map[string][]string {
+	bindings := make(map[string][]string)
+	for _, info := range InputActions {
+		bindings[info.name] = append([]string(nil), info.defaults...)
+	}
+	return bindings
+}
+
+// Build the map from config file bindings. Bad entries are reported and
+// skipped, actions missing from the config get their defaults.
+func MakeInputMap(config map[string][]string) (InputMap, []error) {
+	var m InputMap
+	var errs []error
+	known := make(map[string]bool)
+	for a := InputAction(0); a < NumInputActions; a++ {
+		info := a.Info()
+		known[info.name] = true
+		names, ok := config[info.name]
+		if !ok {
+			names = info.defaults
+		}
+		for _, name := range names {
+			b, err := ParseBinding(name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", info.name, err))
+				continue
+			}
+			m[a] = append(m[a], b)
+		}
+	}
+	for name := range config {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("unknown action %q", name))
+		}
+	}
+	return m, errs
+}
+
+// Config file form, for saving
+func (m *InputMap) Config() map[string][]string {
+	config := make(map[string][]string)
+	for a, bindings := range m {
+		names := []string{}
+		for _, b := range bindings {
+			names = append(names, b.String())
+		}
+		config[InputAction(a).Info().name] = names
+	}
+	return config
+}
+
+func (m *InputMap) Is(a InputAction, key glfw.Key, mods glfw.ModifierKey) bool {
+	for _, b := range m[a] {
+		if b.Matches(key, mods) {
+			return true
+		}
+	}
+	return false
+}
+
+// Make b the only binding for a
+func (m *InputMap) Bind(a InputAction, b Binding) {
+	m[a] = []Binding{b}
+}
+
+// First binding for a, for feeding other devices through the key handlers
+func (m *InputMap) First(a InputAction) (Binding, bool) {
+	if len(m[a]) == 0 {
+		return Binding{glfw.KeyUnknown, 0}, false
+	}
+	return m[a][0], true
+}
+
+// "Left/Right" style list of the keys for a
+func (m *InputMap) Describe(a InputAction) string {
+	if len(m[a]) == 0 {
+		return "NONE"
+	}
+	var names []string
+	for _, b := range m[a] {
+		names = append(names, b.String())
+	}
+	return strings.Join(names, "/")
+}
+
+func contextsOverlap(a, b InputContext) bool {
+	return a == b || a == ContextGlobal || b == ContextGlobal
+}
+
+// A key bound to two actions that are listened for at the same time
+type BindingConflict struct {
+	binding Binding
+	actions [2]InputAction
+}
+
+func (c BindingConflict) Error() string {
+	return fmt.Sprintf("%s is bound to both %s and %s",
+		c.binding, c.actions[0].Info().label, c.actions[1].Info().label)
+}
+
+// Pairs of actions that can't be told apart, because a key press would
+// match a binding for each. With shift left free that's the same key with
+// the same modifiers give or take shift: Enter clashes with Shift+Enter but
+// not Alt+Enter.
+func (m *InputMap) Conflicts() []BindingConflict {
+	var conflicts []BindingConflict
+	for a := InputAction(0); a < NumInputActions; a++ {
+		for b := a + 1; b < NumInputActions; b++ {
+			if !contextsOverlap(a.Info().context, b.Info().context) {
+				continue
+			}
+			for _, ba := range m[a] {
+				for _, bb := range m[b] {
+					if ba.Matches(bb.key, bb.mods) || bb.Matches(ba.key, ba.mods) {
+						conflicts = append(conflicts, BindingConflict{ba, [2]InputAction{a, b}})
+					}
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+func (m *InputMap) Conflicted(a InputAction) bool {
+	for _, c := range m.Conflicts() {
+		if c.actions[0] == a || c.actions[1] == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys held down right now, kept up to date from key events so game input
+// can be read as state each tick rather than added up from presses and
+// releases
+type KeyState struct {
+	held map[glfw.Key]bool
+	//modifiers from the latest event
+	mods glfw.ModifierKey
+}
+
+var gKeys KeyState
+
+func (k *KeyState) Event(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+	if k.held == nil {
+		k.held = make(map[glfw.Key]bool)
+	}
+	k.mods = mods
+	switch action {
+	case glfw.Press:
+		k.held[key] = true
+	case glfw.Release:
+		delete(k.held, key)
+	}
+}
+
+// Forget everything held, for when releases can't be trusted to arrive
+func (k *KeyState) Reset() {
+	k.held = nil
+	k.mods = 0
+}
+
+func (k *KeyState) Down(key glfw.Key) bool {
+	return k.held[key]
+}
+
+// Whether any binding for a is held down
+func (m *InputMap) Held(a InputAction, keys *KeyState) bool {
+	for _, b := range m[a] {
+		if keys.Down(b.key) && b.Matches(b.key, keys.mods) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"github.com/CandleEnds/go-breakout/sim"
+	"os"
+	"time"
+)
+
+var (
+	gLevelSeed = flag.Int64("seed", 0, "play levels generated from this seed, 0 for the classic layout")
+	gLevelFile = flag.String("level", "", "play the level saved in this file, every level")
+	//rows come from -seed or -level if given, otherwise a new seed each game
+	gPressureFile = flag.String("pressure", "", "settings for pressure mode from this JSON file, over the defaults")
+)
+
+// Levels for a generator seed, 0 being the classic layout
+func SeedLevels(seed int64) sim.LevelSource {
+	if seed == 0 {
+		return sim.ClassicLevels{}
+	}
+	return sim.GeneratedLevels{Seed: seed}
+}
+
+// Levels for a local game, from the command line flags
+func LocalLevels() (sim.LevelSource, error) {
+	if *gLevelFile == "" {
+		return SeedLevels(*gLevelSeed), nil
+	}
+	f, err := os.Open(*gLevelFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	level, err := sim.LoadLevel(f)
+	if err != nil {
+		return nil, err
+	}
+	return sim.LevelList{level}, nil
+}
+
+// Pressure settings for a local game, from the command line flags
+func LocalPressure() (*sim.Pressure, error) {
+	p := sim.DefaultPressure
+	if *gPressureFile != "" {
+		f, err := os.Open(*gPressureFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		loaded, err := sim.LoadPressure(f)
+		if err != nil {
+			return nil, err
+		}
+		p = *loaded
+	}
+	if *gLevelFile == "" && *gLevelSeed == 0 {
+		p.Rows = sim.GeneratedRows{Seed: time.Now().UnixNano()}
+		return &p, nil
+	}
+	levels, err := LocalLevels()
+	if err != nil {
+		return nil, err
+	}
+	p.Rows = sim.LevelRows{Levels: levels}
+	return &p, nil
+}
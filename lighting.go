@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+// Must match MAX_POINT_LIGHTS in the fragment shaders
+const MaxPointLights = 4
+
+// Everything here is in world space, after the cylinder warp
+
+type DirectionalLight struct {
+	//towards the light
+	direction mgl.Vec3
+	color     mgl.Vec3
+}
+
+type PointLight struct {
+	pos   mgl.Vec3
+	color mgl.Vec3
+	//no light reaches past this distance
+	radius float64
+}
+
+type Lighting struct {
+	name     string
+	ambient  mgl.Vec3
+	sun      DirectionalLight
+	specular float64
+	//Blinn-Phong exponent, higher is a tighter highlight
+	shininess float64
+
+	//rebuilt every frame from whatever is glowing, see SetPointLights
+	points []PointLight
+	//camera position, for specular highlights
+	eye mgl.Vec3
+}
+
+// Lighting setups selectable from the options menu
+var LightingPresets = []Lighting{
+	{
+		name:      "DAY",
+		ambient:   mgl.Vec3{0.45, 0.45, 0.5},
+		sun:       DirectionalLight{mgl.Vec3{0.3, 0.8, 0.6}, mgl.Vec3{0.7, 0.7, 0.65}},
+		specular:  0.5,
+		shininess: 32,
+	},
+	{
+		name:      "DUSK",
+		ambient:   mgl.Vec3{0.3, 0.22, 0.3},
+		sun:       DirectionalLight{mgl.Vec3{-0.6, 0.3, 0.7}, mgl.Vec3{0.9, 0.55, 0.35}},
+		specular:  0.6,
+		shininess: 24,
+	},
+	{
+		name:      "NIGHT",
+		ambient:   mgl.Vec3{0.08, 0.08, 0.15},
+		sun:       DirectionalLight{mgl.Vec3{0.2, 1, 0.4}, mgl.Vec3{0.15, 0.15, 0.3}},
+		specular:  0.8,
+		shininess: 48,
+	},
+}
+
+var gLighting = LightingPresets[0]
+
+// The ball carries a light around with it
+var (
+	BallLightColor  = mgl.Vec3{1, 0.85, 0.6}
+	BallLightRadius = 4.0
+)
+
+// Switch to another preset, keeping the per-frame point lights
+func (l *Lighting) UsePreset(preset Lighting) {
+	points, eye := l.points, l.eye
+	*l = preset
+	l.points, l.eye = points, eye
+}
+
+// Index of the current preset in LightingPresets, by name
+func (l *Lighting) PresetIndex() int {
+	for i, p := range LightingPresets {
+		if p.name == l.name {
+			return i
+		}
+	}
+	return 0
+}
+
+// Only the first MaxPointLights are used
+func (l *Lighting) SetPointLights(points []PointLight) {
+	if len(points) > MaxPointLights {
+		points = points[:MaxPointLights]
+	}
+	l.points = points
+}
+
+func setVec3Uniform(program uint32, name string, v mgl.Vec3) {
+	gl.Uniform3f(glUniformLoc(program, name), float32(v[0]), float32(v[1]), float32(v[2]))
+}
+
+func setLightingUniforms(program uint32) {
+	l := &gLighting
+	setVec3Uniform(program, "ambientColor", l.ambient)
+	setVec3Uniform(program, "lightDirection", l.sun.direction.Normalize())
+	setVec3Uniform(program, "lightColor", l.sun.color)
+	setVec3Uniform(program, "eyePos", l.eye)
+	gl.Uniform1f(glUniformLoc(program, "specularStrength"), float32(l.specular))
+	gl.Uniform1f(glUniformLoc(program, "shininess"), float32(l.shininess))
+
+	var pos, color [MaxPointLights * 3]float32
+	var radius [MaxPointLights]float32
+	for i, p := range l.points {
+		for k := 0; k < 3; k++ {
+			pos[i*3+k] = float32(p.pos[k])
+			color[i*3+k] = float32(p.color[k])
+		}
+		radius[i] = float32(p.radius)
+	}
+	gl.Uniform1i(glUniformLoc(program, "numPointLights"), int32(len(l.points)))
+	gl.Uniform3fv(glUniformLoc(program, "pointLightPos"), MaxPointLights, &pos[0])
+	gl.Uniform3fv(glUniformLoc(program, "pointLightColor"), MaxPointLights, &color[0])
+	gl.Uniform1fv(glUniformLoc(program, "pointLightRange"), MaxPointLights, &radius[0])
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"strings"
+	"time"
+)
+
+type MenuItem struct {
+	// called every frame so items can show their current value
+	label  func() string
+	action func()
+}
+
+func Label(s string) func() string {
+	return func() string { return s }
+}
+
+// Vertical list of items, one selected, navigated with up/down and enter
+type Menu struct {
+	title    string
+	message  string
+	items    []MenuItem
+	selected int
+}
+
+var (
+	MenuTitleColor    = mgl.Vec4{1, 0.8, 0.2, 1}
+	MenuSelectedColor = mgl.Vec4{1, 1, 1, 1}
+	MenuItemColor     = mgl.Vec4{0.6, 0.6, 0.6, 1}
+)
+
+func (m *Menu) HandleKey(key glfw.Key, mods glfw.ModifierKey) bool {
+	if len(m.items) == 0 {
+		return false
+	}
+	switch {
+	case gInput.Is(ActionMenuUp, key, mods):
+		m.selected = (m.selected + len(m.items) - 1) % len(m.items)
+	case gInput.Is(ActionMenuDown, key, mods):
+		m.selected = (m.selected + 1) % len(m.items)
+	case gInput.Is(ActionSelect, key, mods):
+		m.items[m.selected].action()
+	default:
+		return false
+	}
+	return true
+}
+
+// Log err and tell the player something went wrong, in words the bitmap
+// font can draw
+func (m *Menu) ShowError(message string, err error) {
+	fmt.Println(err)
+	m.message = message
+}
+
+// Title, message and items centred on screen as one block
+func (m *Menu) Draw(h *HUD) {
+	var lines []string
+	var colors []mgl.Vec4
+	if m.title != "" {
+		lines = append(lines, m.title, "")
+		colors = append(colors, MenuTitleColor, MenuTitleColor)
+	}
+	if m.message != "" {
+		lines = append(lines, m.message, "")
+		colors = append(colors, MenuItemColor, MenuItemColor)
+	}
+	for i, item := range m.items {
+		if i == m.selected {
+			lines = append(lines, "> "+item.label()+" <")
+			colors = append(colors, MenuSelectedColor)
+		} else {
+			lines = append(lines, item.label())
+			colors = append(colors, MenuItemColor)
+		}
+	}
+
+	screen := h.ScreenSize()
+	blockHeight := float64(len(lines)) * h.text.LineHeight()
+	y := (screen[1] - blockHeight) / 2
+	for i, line := range lines {
+		width := h.text.Measure(line)[0]
+		h.LabelAt(line, mgl.Vec2{(screen[0] - width) / 2, y}, colors[i])
+		y += h.text.LineHeight()
+	}
+}
+
+type MenuState struct {
+	menu Menu
+	// dims and shows the state below instead of replacing it
+	overlay bool
+	// escape key, nil to ignore it
+	back func()
+}
+
+func (m *MenuState) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	if action != glfw.Press && action != glfw.Repeat {
+		return false
+	}
+	if gInput.Is(ActionBack, key, mods) && m.back != nil {
+		m.back()
+		return true
+	}
+	return m.menu.HandleKey(key, mods)
+}
+
+func (m *MenuState) Update() {
+}
+
+func (m *MenuState) Draw(elapsed time.Duration) {
+	gHUD.Begin()
+	defer gHUD.End()
+	if m.overlay {
+		gHUD.Dim()
+	}
+	m.menu.Draw(gHUD)
+}
+
+func (m *MenuState) IsOverlay() bool {
+	return m.overlay
+}
+
+func Quit() {
+	gWindow.SetShouldClose(true)
+}
+
+func OnOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// Seconds the title waits for a key before showing the demo
+const AttractDelay = 20
+
+// Title menu, which runs a demo game when left alone
+type TitleState struct {
+	MenuState
+	stageSize mgl.Vec2
+	//ticks since the last key
+	idle int
+}
+
+func MakeTitleState(stageSize mgl.Vec2) *TitleState {
+	s := &TitleState{stageSize: stageSize}
+	s.menu.title = WindowTitle
+	s.menu.items = []MenuItem{
+		{Label("1 PLAYER"), func() { s.start(stageSize, sim.ModeSingle) }},
+		{Label("2 PLAYER CO-OP"), func() { s.start(stageSize, sim.ModeCoop) }},
+		{Label("2 PLAYER VERSUS"), func() { s.start(stageSize, sim.ModeVersus) }},
+		{Label("PRESSURE"), func() { s.startPressure(stageSize) }},
+		{Label("OPTIONS"), func() { gStates.Push(MakeOptionsState()) }},
+		{Label("QUIT"), Quit},
+	}
+	s.back = Quit
+	return s
+}
+
+func (s *TitleState) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	s.idle = 0
+	return s.MenuState.HandleKey(key, scancode, action, mods)
+}
+
+func (s *TitleState) Update() {
+	s.idle++
+	if s.idle < int(AttractDelay*time.Second/TimePerUpdate) {
+		return
+	}
+	s.idle = 0
+	demo, err := MakeDemoState(s.stageSize)
+	if err != nil {
+		s.menu.ShowError("COULD NOT START DEMO", err)
+		return
+	}
+	gStates.Push(demo)
+}
+
+// Leave any game in progress, network and server games included
+func QuitToTitle(stageSize mgl.Vec2) *TitleState {
+	gNet.Close()
+	gNet = nil
+	gServer.Close()
+	gServer = nil
+	title := MakeTitleState(stageSize)
+	gStates.Reset(title)
+	return title
+}
+
+// Replace the title with a new game
+func (s *MenuState) start(stageSize mgl.Vec2, mode sim.GameMode) {
+	play, err := MakePlayingState(stageSize, mode)
+	if err != nil {
+		s.menu.ShowError("COULD NOT START GAME", err)
+		return
+	}
+	gStates.Replace(play)
+}
+
+// Replace the title with a single player pressure game
+func (s *MenuState) startPressure(stageSize mgl.Vec2) {
+	play, err := MakePressureState(stageSize, sim.ModeSingle)
+	if err != nil {
+		s.menu.ShowError("COULD NOT START GAME", err)
+		return
+	}
+	gStates.Replace(play)
+}
+
+// Score line for the end of level and game over menus
+func ScoreMessage(world *sim.World) string {
+	if world.Mode != sim.ModeVersus {
+		return fmt.Sprintf("SCORE %d", world.Score())
+	}
+	p1, p2 := world.Players[0].Score, world.Players[1].Score
+	winner := world.Winner()
+	if winner < 0 {
+		return fmt.Sprintf("DRAW %d - %d", p1, p2)
+	}
+	return fmt.Sprintf("P%d WINS %d - %d", winner+1, p1, p2)
+}
+
+func MakeOptionsState() *MenuState {
+	s := &MenuState{}
+	s.menu.title = "OPTIONS"
+	s.menu.items = []MenuItem{
+		{
+			func() string { return "FULLSCREEN " + OnOff(IsFullscreen(gWindow)) },
+			func() { ToggleFullscreen(gWindow) },
+		},
+		{
+			func() string { return "SHOW FPS " + OnOff(gOptions.ShowFPS) },
+			func() { gOptions.ShowFPS = !gOptions.ShowFPS },
+		},
+		{
+			func() string { return "BALL TRAIL " + OnOff(gOptions.BallTrail) },
+			func() { gOptions.BallTrail = !gOptions.BallTrail },
+		},
+		{
+			func() string { return "LIGHTING " + gLighting.name },
+			func() {
+				next := (gLighting.PresetIndex() + 1) % len(LightingPresets)
+				gLighting.UsePreset(LightingPresets[next])
+				gOptions.Lighting = gLighting.name
+			},
+		},
+		{
+			func() string {
+				return fmt.Sprintf("STICK DEAD ZONE %d%%", int(math.Round(gOptions.Gamepad.DeadZone*100)))
+			},
+			func() {
+				//5% steps up to 50%, then back to none
+				zone := math.Round(gOptions.Gamepad.DeadZone*20+1) / 20
+				if zone > 0.5 {
+					zone = 0
+				}
+				gOptions.Gamepad.DeadZone = zone
+			},
+		},
+		{
+			func() string { return "CONTROL " + strings.ToUpper(gOptions.Control) },
+			func() {
+				if gOptions.Control == "mouse" {
+					gOptions.Control = "keyboard"
+				} else {
+					gOptions.Control = "mouse"
+				}
+			},
+		},
+		{
+			func() string { return fmt.Sprintf("MOUSE SENSITIVITY %.2f", gOptions.MouseSensitivity) },
+			func() {
+				//quarter steps from 0.25 to 3
+				s := math.Round(gOptions.MouseSensitivity*4+1) / 4
+				if s > 3 {
+					s = 0.25
+				}
+				gOptions.MouseSensitivity = s
+			},
+		},
+		{Label("CONTROLS"), func() { gStates.Push(MakeControlsState()) }},
+		{Label("POST EFFECTS"), func() { gStates.Push(MakeEffectsState()) }},
+		{Label("BACK"), closeOptions},
+	}
+	if len(gPost.passes) == 0 {
+		//no render targets on this driver, see MakePostChain
+		n := len(s.menu.items)
+		s.menu.items = append(s.menu.items[:n-2], s.menu.items[n-1])
+	}
+	s.back = closeOptions
+	return s
+}
+
+// Leave an options screen, keeping the changes for next time
+func closeOptions() {
+	if err := SaveOptions(*gConfigFile); err != nil {
+		fmt.Println("saving options:", err)
+	}
+	gStates.Pop()
+}
+
+func effectItem(label, name string) MenuItem {
+	return MenuItem{
+		func() string { return label + " " + OnOff(gOptions.EffectEnabled(name)) },
+		func() { gOptions.ToggleEffect(name) },
+	}
+}
+
+func MakeEffectsState() *MenuState {
+	s := &MenuState{}
+	s.menu.title = "POST EFFECTS"
+	s.menu.items = []MenuItem{
+		effectItem("BLOOM", "bloom"),
+		effectItem("VIGNETTE", "vignette"),
+		effectItem("CRT", "crt"),
+		{
+			func() string {
+				if gOptions.ColorBlind == "" {
+					return "COLOUR BLIND OFF"
+				}
+				return "COLOUR BLIND " + strings.ToUpper(gOptions.ColorBlind)
+			},
+			func() {
+				//OFF, then each mode in turn
+				next := 0
+				for i, m := range ColorBlindModes {
+					if m.name == gOptions.ColorBlind {
+						next = i + 1
+					}
+				}
+				if next == len(ColorBlindModes) {
+					gOptions.ColorBlind = ""
+				} else {
+					gOptions.ColorBlind = ColorBlindModes[next].name
+				}
+			},
+		},
+		{Label("BACK"), closeOptions},
+	}
+	s.back = closeOptions
+	return s
+}
+
+// One item per action, selecting it waits for a new key
+func MakeControlsState() *MenuState {
+	s := &MenuState{}
+	s.menu.title = "CONTROLS"
+	for a := InputAction(0); a < NumInputActions; a++ {
+		a := a
+		s.menu.items = append(s.menu.items, MenuItem{
+			func() string {
+				label := a.Info().label + " " + strings.ToUpper(gInput.Describe(a))
+				if gInput.Conflicted(a) {
+					return "! " + label
+				}
+				return label
+			},
+			func() { gStates.Push(MakeRebindState(s, a)) },
+		})
+	}
+	s.menu.items = append(s.menu.items,
+		MenuItem{Label("RESET TO DEFAULTS"), func() {
+			gOptions.Bindings = DefaultBindings()
+			gInput, _ = MakeInputMap(gOptions.Bindings)
+			s.menu.message = ""
+		}},
+		MenuItem{Label("BACK"), closeOptions},
+	)
+	s.back = closeOptions
+	return s
+}
+
+// Waits for the next key press and binds it to an action
+type RebindState struct {
+	menu     Menu
+	controls *MenuState
+	action   InputAction
+}
+
+func MakeRebindState(controls *MenuState, a InputAction) *RebindState {
+	r := &RebindState{controls: controls, action: a}
+	r.menu.title = a.Info().label
+	r.menu.message = "PRESS A KEY, ESCAPE TO CANCEL"
+	return r
+}
+
+func (r *RebindState) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	if action != glfw.Press {
+		return false
+	}
+	if key == glfw.KeyEscape {
+		gStates.Pop()
+		return true
+	}
+	if _, named := keyNames[key]; !named || isModifierKey(key) {
+		//wait for the key the modifiers go with
+		return true
+	}
+
+	gInput.Bind(r.action, Binding{key, mods})
+	gOptions.Bindings = gInput.Config()
+	r.controls.menu.message = ""
+	if conflicts := gInput.Conflicts(); len(conflicts) > 0 {
+		r.controls.menu.message = strings.ToUpper(conflicts[0].Error())
+	}
+	gStates.Pop()
+	return true
+}
+
+func isModifierKey(key glfw.Key) bool {
+	switch key {
+	case glfw.KeyLeftShift, glfw.KeyRightShift, glfw.KeyLeftControl, glfw.KeyRightControl,
+		glfw.KeyLeftAlt, glfw.KeyRightAlt:
+		return true
+	}
+	return false
+}
+
+func (r *RebindState) Update() {
+}
+
+func (r *RebindState) Draw(elapsed time.Duration) {
+	gHUD.Begin()
+	defer gHUD.End()
+	gHUD.Dim()
+	r.menu.Draw(gHUD)
+}
+
+func (r *RebindState) IsOverlay() bool {
+	return true
+}
+
+func MakePauseState(world *sim.World) *MenuState {
+	s := &MenuState{overlay: true}
+	s.menu.title = "PAUSED"
+	s.menu.items = []MenuItem{
+		{Label("RESUME"), gStates.Pop},
+		{Label("OPTIONS"), func() { gStates.Push(MakeOptionsState()) }},
+		{Label("QUIT TO TITLE"), func() { QuitToTitle(world.StageSize) }},
+	}
+	s.back = gStates.Pop
+	return s
+}
+
+func MakeLevelCompleteState(world *sim.World) *MenuState {
+	s := &MenuState{overlay: true}
+	s.menu.title = fmt.Sprintf("LEVEL %d COMPLETE", world.Level)
+	s.menu.message = ScoreMessage(world)
+	s.menu.items = []MenuItem{
+		{Label("CONTINUE"), func() {
+			world.NextLevel()
+			gStates.Pop()
+		}},
+	}
+	return s
+}
+
+func MakeGameOverState(world *sim.World, replay bool) *MenuState {
+	s := &MenuState{overlay: true}
+	s.menu.title = "GAME OVER"
+	s.menu.message = ScoreMessage(world)
+	s.menu.items = []MenuItem{
+		{Label("PLAY AGAIN"), func() {
+			play, err := MakeReplayState(world)
+			if err != nil {
+				s.menu.ShowError("COULD NOT START GAME", err)
+				return
+			}
+			gStates.Reset(play)
+		}},
+		{Label("MAIN MENU"), func() { QuitToTitle(world.StageSize) }},
+	}
+	if !replay {
+		s.menu.items = s.menu.items[1:]
+	}
+	return s
+}
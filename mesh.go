@@ -0,0 +1,163 @@
+package main
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// Interleaved vertex layout shared by every mesh: x,y,z, u,v, nx,ny,nz.
+// Positions are in stage units, z points out of the cylinder.
+const VertexFloats = 8
+
+type Mesh struct {
+	vertices []float32
+	indices  []uint16
+}
+
+func (m *Mesh) AddVertex(pos mgl.Vec3, uv mgl.Vec2, normal mgl.Vec3) uint16 {
+	idx := uint16(len(m.vertices) / VertexFloats)
+	m.vertices = append(m.vertices,
+		float32(pos[0]), float32(pos[1]), float32(pos[2]),
+		float32(uv[0]), float32(uv[1]),
+		float32(normal[0]), float32(normal[1]), float32(normal[2]))
+	return idx
+}
+
+func (m *Mesh) AddTri(a, b, c uint16) {
+	m.indices = append(m.indices, a, b, c)
+}
+
+// Corners in counter-clockwise order seen from the front
+func (m *Mesh) AddQuad(a, b, c, d uint16) {
+	m.AddTri(a, b, c)
+	m.AddTri(a, c, d)
+}
+
+// Extrude a convex (y, z) profile along x from 0 to width. Every face gets
+// its own vertices so the shading stays flat, and each face is split into
+// segments along x so it bends smoothly around the cylinder.
+// Profile points run from the back bottom edge round to the back top edge.
+func ExtrudeProfile(m *Mesh, profile []mgl.Vec2, width, height float64, segments int) {
+	for k := 0; k+1 < len(profile); k++ {
+		a, b := profile[k], profile[k+1]
+		d := b.Sub(a)
+		n := mgl.Vec2{-d[1], d[0]}.Normalize()
+		normal := mgl.Vec3{0, n[0], n[1]}
+
+		var prevA, prevB uint16
+		for i := 0; i <= segments; i++ {
+			x := width * float64(i) / float64(segments)
+			u := float64(i) / float64(segments)
+			va := m.AddVertex(mgl.Vec3{x, a[0], a[1]}, mgl.Vec2{u, a[0] / height}, normal)
+			vb := m.AddVertex(mgl.Vec3{x, b[0], b[1]}, mgl.Vec2{u, b[0] / height}, normal)
+			if i > 0 {
+				m.AddQuad(prevA, va, vb, prevB)
+			}
+			prevA, prevB = va, vb
+		}
+	}
+
+	// flat end caps, fanned from the first profile point
+	for _, end := range []struct {
+		x float64
+		n float64
+	}{{0, -1}, {width, 1}} {
+		normal := mgl.Vec3{end.n, 0, 0}
+		var ring []uint16
+		for _, p := range profile {
+			ring = append(ring, m.AddVertex(mgl.Vec3{end.x, p[0], p[1]},
+				mgl.Vec2{(end.n + 1) / 2, p[0] / height}, normal))
+		}
+		for k := 1; k+1 < len(ring); k++ {
+			m.AddTri(ring[0], ring[k], ring[k+1])
+		}
+	}
+}
+
+// Brick of size (width, height) standing depth out of the stage, with its
+// top and bottom front edges bevelled
+func VertexifyBrick(size mgl.Vec2, depth, bevel float64, segments int) Mesh {
+	bevel = math.Min(bevel, math.Min(size[1]/2, depth))
+	h := size[1]
+	profile := []mgl.Vec2{
+		{0, 0},
+		{0, depth - bevel},
+		{bevel, depth},
+		{h - bevel, depth},
+		{h, depth - bevel},
+		{h, 0},
+	}
+	var m Mesh
+	ExtrudeProfile(&m, profile, size[0], h, segments)
+	return m
+}
+
+// Half-round bar along x filling size and bulging out of the stage by
+// size[1]/2, e.g. the paddle. sides is the number of facets across the
+// bar, normals are radial so it shades as a smooth curve.
+func VertexifyBar(size mgl.Vec2, sides, segments int) Mesh {
+	var m Mesh
+	r := size[1] / 2
+	row := uint16(segments + 1)
+	for k := 0; k <= sides; k++ {
+		// bottom edge, over the front, to the top edge
+		a := -math.Pi/2 + math.Pi*float64(k)/float64(sides)
+		y := r + r*math.Sin(a)
+		z := r * math.Cos(a)
+		normal := mgl.Vec3{0, math.Sin(a), math.Cos(a)}
+		for i := 0; i <= segments; i++ {
+			u := float64(i) / float64(segments)
+			m.AddVertex(mgl.Vec3{size[0] * u, y, z}, mgl.Vec2{u, y / size[1]}, normal)
+			if k > 0 && i > 0 {
+				c := uint16(k)*row + uint16(i)
+				m.AddQuad(c-row-1, c-row, c, c-1)
+			}
+		}
+	}
+
+	// half-disc end caps
+	for _, end := range []float64{0, 1} {
+		normal := mgl.Vec3{end*2 - 1, 0, 0}
+		x := size[0] * end
+		center := m.AddVertex(mgl.Vec3{x, r, 0}, mgl.Vec2{end, 0.5}, normal)
+		var prev uint16
+		for k := 0; k <= sides; k++ {
+			a := -math.Pi/2 + math.Pi*float64(k)/float64(sides)
+			y := r + r*math.Sin(a)
+			v := m.AddVertex(mgl.Vec3{x, y, r * math.Cos(a)}, mgl.Vec2{end, y / size[1]}, normal)
+			if k > 0 {
+				m.AddTri(center, prev, v)
+			}
+			prev = v
+		}
+	}
+	return m
+}
+
+// UV sphere of the given radius sitting on the stage, its bounding square
+// in the stage plane runs from (0, 0) to (2*radius, 2*radius)
+func VertexifySphere(radius float64, rings, sectors int) Mesh {
+	var m Mesh
+	center := mgl.Vec3{radius, radius, radius}
+	for i := 0; i <= rings; i++ {
+		phi := math.Pi * float64(i) / float64(rings)
+		for j := 0; j <= sectors; j++ {
+			theta := 2 * math.Pi * float64(j) / float64(sectors)
+			n := mgl.Vec3{
+				math.Sin(phi) * math.Cos(theta),
+				-math.Cos(phi),
+				math.Sin(phi) * math.Sin(theta),
+			}
+			uv := mgl.Vec2{float64(j) / float64(sectors), float64(i) / float64(rings)}
+			m.AddVertex(center.Add(n.Mul(radius)), uv, n)
+		}
+	}
+	row := uint16(sectors + 1)
+	for i := 0; i < rings; i++ {
+		for j := 0; j < sectors; j++ {
+			a := uint16(i)*row + uint16(j)
+			m.AddQuad(a, a+1, a+row+1, a+row)
+		}
+	}
+	return m
+}
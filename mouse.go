@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/CandleEnds/go-breakout/sim"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// Horizontal cursor movement since it was last taken, in window units,
+// plus where the cursor is and which buttons are down for the editor
+type MouseInput struct {
+	lastX   float64
+	lastY   float64
+	hasLast bool
+	dx      float64
+	held    map[glfw.MouseButton]bool
+	//cursor hidden and locked to the window while playing
+	captured bool
+}
+
+var gMouse MouseInput
+
+func glfwCursorPosCallback(w *glfw.Window, x, y float64) {
+	if gMouse.hasLast {
+		gMouse.dx += x - gMouse.lastX
+	}
+	gMouse.lastX = x
+	gMouse.lastY = y
+	gMouse.hasLast = true
+}
+
+func glfwMouseButtonCallback(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	if gMouse.held == nil {
+		gMouse.held = make(map[glfw.MouseButton]bool)
+	}
+	switch action {
+	case glfw.Press:
+		gMouse.held[button] = true
+	case glfw.Release:
+		delete(gMouse.held, button)
+	}
+}
+
+func (m *MouseInput) Reset() {
+	m.hasLast = false
+	m.dx = 0
+	m.held = nil
+}
+
+// Cursor position in window units from the top left, false until the
+// cursor has moved over the window
+func (m *MouseInput) Pos() (mgl.Vec2, bool) {
+	return mgl.Vec2{m.lastX, m.lastY}, m.hasLast
+}
+
+func (m *MouseInput) Held(button glfw.MouseButton) bool {
+	return m.held[button]
+}
+
+func (m *MouseInput) TakeDelta() float64 {
+	dx := m.dx
+	m.dx = 0
+	return dx
+}
+
+// Capture the cursor while a mouse-controlled game is running, and give it
+// back in menus
+func (m *MouseInput) UpdateCapture(w *glfw.Window) {
+	play, playing := gStates.Top().(*PlayingState)
+	capture := playing && !play.demo && gOptions.Control == "mouse"
+	if capture == m.captured {
+		return
+	}
+	m.captured = capture
+	if capture {
+		w.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	} else {
+		w.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+	//movement from before the switch isn't meant for the paddle
+	m.Reset()
+}
+
+// Steers the paddle by however far the mouse moved. The paddle can't go
+// faster than its keyboard speed, so big movements play out over several
+// ticks instead of teleporting it.
+type MouseController struct {
+	//the move keys still work alongside the mouse
+	KeyboardController
+	//stage units the paddle still has to travel
+	owed float64
+}
+
+func (m *MouseController) Update(p *sim.Paddle, stageSize mgl.Vec2) {
+	m.KeyboardController.Update(p, stageSize)
+	if gScreen.windowWidth > 0 {
+		//the width of the window moves the paddle once round at sensitivity 1
+		dx := gMouse.TakeDelta() / float64(gScreen.windowWidth)
+		m.owed += dx * stageSize[0] * gOptions.MouseSensitivity
+	}
+	//a wild flick shouldn't keep the paddle going for seconds
+	limit := stageSize[0] / 2
+	m.owed = math.Max(-limit, math.Min(limit, m.owed))
+
+	step := math.Max(-p.Speed, math.Min(p.Speed, m.owed))
+	m.owed -= step
+	p.Steer(step / p.Speed)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/CandleEnds/go-breakout/netplay"
+	"github.com/CandleEnds/go-breakout/remote"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+var (
+	gNetHost = flag.String("host", "", "host a network game on this address, e.g. :7777")
+	gNetJoin = flag.String("join", "", "join the network game hosted at this address, e.g. 192.168.1.5:7777")
+	gNetMode = flag.String("mode", "coop", "network game mode when hosting: coop or versus")
+
+	gServerAddr = flag.String("server", "", "play on the game server at this address, e.g. 192.168.1.5:7778")
+	gSpectate   = flag.Bool("spectate", false, "with -server, watch the game instead of taking a paddle")
+)
+
+// Peer-to-peer game in progress, nil when not playing one
+var gNet *netplay.Session
+
+// Connection to a dedicated server, nil when not playing on one
+var gServer *remote.Client
+
+// Start hosting, joining or connecting to a server from the command line
+// flags, if any of them is set
+func StartNetSession() error {
+	set := 0
+	for _, addr := range []string{*gNetHost, *gNetJoin, *gServerAddr} {
+		if addr != "" {
+			set++
+		}
+	}
+	switch {
+	case set > 1:
+		return errors.New("only one of -host, -join and -server can be used")
+	case *gServerAddr != "":
+		var err error
+		gServer, err = remote.Dial(*gServerAddr, *gSpectate)
+		return err
+	case *gNetHost != "":
+		mode, err := sim.ParseGameMode(*gNetMode)
+		if err != nil {
+			return err
+		}
+		if mode.Players() != 2 {
+			return errors.New("a network game needs a two player mode, coop or versus")
+		}
+		gNet, err = netplay.Host(*gNetHost, mode, *gLevelSeed)
+		return err
+	case *gNetJoin != "":
+		var err error
+		gNet, err = netplay.Join(*gNetJoin)
+		return err
+	}
+	return nil
+}
+
+// Waits for the other player, then starts the network game
+type ConnectState struct {
+	MenuState
+	stageSize mgl.Vec2
+}
+
+func MakeConnectState(stageSize mgl.Vec2) *ConnectState {
+	s := &ConnectState{stageSize: stageSize}
+	if gNet.IsHost() {
+		s.menu.title = "WAITING FOR PLAYER"
+		s.menu.message = "HOSTING ON " + gNet.LocalAddr().String()
+	} else {
+		s.menu.title = "JOINING GAME"
+		s.menu.message = "CONNECTING TO " + gNet.RemoteAddr().String()
+	}
+	cancel := func() { QuitToTitle(stageSize) }
+	s.menu.items = []MenuItem{{Label("CANCEL"), cancel}}
+	s.back = cancel
+	return s
+}
+
+func (s *ConnectState) Update() {
+	if err := gNet.Err(); err != nil {
+		QuitToTitle(s.stageSize).menu.ShowError("COULD NOT CONNECT", err)
+		return
+	}
+	if !gNet.Started() {
+		return
+	}
+	play, err := MakeNetPlayingState(s.stageSize, gNet)
+	if err != nil {
+		QuitToTitle(s.stageSize).menu.ShowError("COULD NOT START GAME", err)
+		return
+	}
+	gStates.Replace(play)
+}
@@ -0,0 +1,510 @@
+// Two player games over UDP with rollback. Each peer runs the whole
+// simulation and they only exchange inputs.
+package netplay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	"net"
+	"time"
+)
+
+const (
+	// Ticks between sampling local input and applying it. Covers most of a
+	// LAN round trip, so remote input usually arrives before it's needed
+	// and rollbacks stay rare.
+	InputDelay = 2
+	// Furthest the simulation runs ahead of the last confirmed remote
+	// input. Past this it waits, as plain lockstep would.
+	RollbackWindow = 8
+	// Silence from the other peer for this long drops the connection
+	Timeout = 5 * time.Second
+	// Resend interval for hellos, and for inputs while nothing new is
+	// being simulated, e.g. when paused
+	ResendInterval = 250 * time.Millisecond
+	// Most inputs carried by one packet
+	MaxInputs = 64
+	// Confirmed checksums kept for comparing with a lagging peer
+	ChecksumHistory = 120
+
+	// Bump when packets or the simulation change, peers have to match
+	ProtocolVersion = 4
+	magic           = 0x4272
+)
+
+type packetKind uint8
+
+const (
+	// joining peer to host, repeated until welcomed
+	packetHello packetKind = iota + 1
+	// host to joining peer, carries the game mode
+	packetWelcome
+	// inputs not yet acknowledged, plus a checksum
+	packetInput
+	// leaving the game
+	packetBye
+)
+
+type packetHeader struct {
+	Magic   uint16
+	Version uint8
+	Kind    packetKind
+}
+
+type welcomeBody struct {
+	Mode uint8
+	Seed int64
+}
+
+// Followed by Count TickInputs for consecutive ticks from Start, then an
+// inputsChecksum
+type inputsHeader struct {
+	//sender has the receiver's inputs for every tick before Ack
+	Ack   uint32
+	Start uint32
+	Count uint8
+}
+
+type inputsChecksum struct {
+	//-1 when the sender hasn't confirmed any tick yet
+	Tick int32
+	Sum  uint32
+}
+
+type packet struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// A two player game over UDP. Each peer runs the whole simulation and
+// they only exchange inputs, so the simulation has to be deterministic.
+// Remote inputs that haven't arrived yet are predicted; when the real one
+// turns out different the world is rewound to that tick and run forward
+// again.
+type Session struct {
+	conn   *net.UDPConn
+	remote *net.UDPAddr
+	host   bool
+	mode   sim.GameMode
+	//levels are generated from this, 0 for the classic ones. The host's
+	//-seed, which the joining peer is told.
+	seed    int64
+	packets chan packet
+	//player index of this peer, the host is player 0
+	local   int
+	started bool
+	err     error
+
+	lastSend  time.Time
+	lastHeard time.Time
+
+	//next tick to simulate
+	tick int
+	//inputs[player][tick]. Local ones are known InputDelay ticks
+	//ahead, remote ones up to confirmed.
+	inputs [2][]sim.TickInput
+	//remote inputs are known for every tick before this
+	confirmed int
+	//remote has our inputs for every tick before this
+	acked int
+	//remote input each simulated tick ran with, to spot mispredictions
+	used []sim.TickInput
+	//earliest tick that ran with a wrong prediction, -1 for none
+	rollback int
+	//world at the start of each tick that might still be rewound to
+	snapshots map[int]*sim.WorldSnapshot
+	//checksums are known for every tick before this
+	verified  int
+	checksums map[int]uint32
+
+	controllers [2]*sim.InputController
+	//number of rewinds, for spotting a bad connection
+	rollbacks int
+}
+
+// Listen on addr for a peer to join a game of mode, with levels from seed
+func Host(addr string, mode sim.GameMode, seed int64) (*Session, error) {
+	local, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		return nil, err
+	}
+	s := makeSession(conn, nil)
+	s.host = true
+	s.mode = mode
+	s.seed = seed
+	return s, nil
+}
+
+// Join the game hosted at addr, which says what mode and seed it is
+func Join(addr string) (*Session, error) {
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	s := makeSession(conn, remote)
+	s.local = 1
+	return s, nil
+}
+
+func makeSession(conn *net.UDPConn, remote *net.UDPAddr) *Session {
+	s := &Session{
+		conn:      conn,
+		remote:    remote,
+		packets:   make(chan packet, 256),
+		lastHeard: time.Now(),
+		rollback:  -1,
+		snapshots: make(map[int]*sim.WorldSnapshot),
+		checksums: make(map[int]uint32),
+		confirmed: InputDelay,
+		acked:     InputDelay,
+	}
+	//nobody has input for the first ticks, both start out idle
+	for i := range s.inputs {
+		s.inputs[i] = make([]sim.TickInput, InputDelay)
+	}
+	go s.receiveLoop(conn)
+	return s
+}
+
+// Reads on its own goroutine so Poll never blocks, ends when the
+// connection is closed
+func (s *Session) receiveLoop(conn *net.UDPConn) {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				close(s.packets)
+				return
+			}
+			//e.g. the peer's port is closed, the timeout deals with it
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		s.packets <- packet{data, from}
+	}
+}
+
+// The host is player 0 and picks the mode and level seed
+func (s *Session) IsHost() bool {
+	return s.host
+}
+
+// Both peers have heard from each other, Mode and Seed are settled
+func (s *Session) Started() bool {
+	return s.started
+}
+
+func (s *Session) Mode() sim.GameMode {
+	return s.mode
+}
+
+// Levels are generated from this, 0 for the classic ones
+func (s *Session) Seed() int64 {
+	return s.seed
+}
+
+// Address this peer listens on
+func (s *Session) LocalAddr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// The other peer, nil while a host waits for one
+func (s *Session) RemoteAddr() net.Addr {
+	if s.remote == nil {
+		return nil
+	}
+	return s.remote
+}
+
+// Times the world has been rewound for a misprediction
+func (s *Session) Rollbacks() int {
+	return s.rollbacks
+}
+
+// Hand the world's paddles over to the session
+func (s *Session) Attach(w *sim.World) {
+	for i, p := range w.Players {
+		s.controllers[i] = &sim.InputController{}
+		p.Paddle.SetController(s.controllers[i])
+	}
+}
+
+func (s *Session) Local() int {
+	return s.local
+}
+
+func (s *Session) Err() error {
+	return s.err
+}
+
+// Process incoming packets and keep the other peer informed. Call once
+// per frame, also while the game is paused or still connecting.
+func (s *Session) Poll() {
+	if s == nil || s.err != nil {
+		return
+	}
+	for received := true; received; {
+		select {
+		case p, ok := <-s.packets:
+			if !ok {
+				return
+			}
+			s.receive(p)
+		default:
+			received = false
+		}
+	}
+	if s.err != nil {
+		return
+	}
+
+	now := time.Now()
+	if s.started && now.Sub(s.lastHeard) > Timeout {
+		s.fail(errors.New("connection timed out"))
+		return
+	}
+	if now.Sub(s.lastSend) < ResendInterval {
+		return
+	}
+	switch {
+	case s.started:
+		s.sendInputs()
+	case !s.host:
+		s.send(packetHello, nil)
+	}
+}
+
+// Leave the game, telling the other peer. Safe on a nil session.
+func (s *Session) Close() {
+	if s == nil || s.conn == nil {
+		return
+	}
+	if s.remote != nil && s.err == nil {
+		s.send(packetBye, nil)
+	}
+	s.conn.Close()
+	s.conn = nil
+	if s.err == nil {
+		s.err = errors.New("session closed")
+	}
+}
+
+func (s *Session) fail(err error) {
+	fmt.Println("network game:", err)
+	s.err = err
+}
+
+func (s *Session) send(kind packetKind, body func(*bytes.Buffer)) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, packetHeader{magic, ProtocolVersion, kind})
+	if body != nil {
+		body(&buf)
+	}
+	s.lastSend = time.Now()
+	//lost packets are resent anyway
+	s.conn.WriteToUDP(buf.Bytes(), s.remote)
+}
+
+func (s *Session) sendInputs() {
+	local := s.inputs[s.local]
+	start := s.acked
+	end := len(local)
+	if end-start > MaxInputs {
+		end = start + MaxInputs
+	}
+	check := inputsChecksum{-1, 0}
+	if s.verified > 0 {
+		check = inputsChecksum{int32(s.verified - 1), s.checksums[s.verified-1]}
+	}
+	s.send(packetInput, func(buf *bytes.Buffer) {
+		binary.Write(buf, binary.BigEndian, inputsHeader{uint32(s.confirmed), uint32(start), uint8(end - start)})
+		binary.Write(buf, binary.BigEndian, local[start:end])
+		binary.Write(buf, binary.BigEndian, check)
+	})
+}
+
+func (s *Session) receive(p packet) {
+	r := bytes.NewReader(p.data)
+	var header packetHeader
+	if binary.Read(r, binary.BigEndian, &header) != nil || header.Magic != magic {
+		return
+	}
+	if header.Version != ProtocolVersion {
+		s.fail(fmt.Errorf("other player runs protocol version %d, this is version %d", header.Version, ProtocolVersion))
+		return
+	}
+
+	if s.host && s.remote == nil && header.Kind == packetHello {
+		fmt.Println("network game: player joined from", p.from)
+		s.remote = p.from
+		s.started = true
+	}
+	if s.remote == nil || !p.from.IP.Equal(s.remote.IP) || p.from.Port != s.remote.Port {
+		//someone else, the game is full
+		return
+	}
+	s.lastHeard = time.Now()
+
+	switch header.Kind {
+	case packetHello:
+		//repeated until the welcome gets through
+		s.send(packetWelcome, func(buf *bytes.Buffer) {
+			binary.Write(buf, binary.BigEndian, welcomeBody{uint8(s.mode), s.seed})
+		})
+	case packetWelcome:
+		var welcome welcomeBody
+		if binary.Read(r, binary.BigEndian, &welcome) != nil || s.started {
+			return
+		}
+		s.mode = sim.GameMode(welcome.Mode)
+		s.seed = welcome.Seed
+		s.started = true
+	case packetInput:
+		s.receiveInputs(r)
+	case packetBye:
+		s.fail(errors.New("the other player left"))
+	}
+}
+
+func (s *Session) receiveInputs(r *bytes.Reader) {
+	var header inputsHeader
+	if binary.Read(r, binary.BigEndian, &header) != nil {
+		return
+	}
+	inputs := make([]sim.TickInput, header.Count)
+	var check inputsChecksum
+	if binary.Read(r, binary.BigEndian, inputs) != nil || binary.Read(r, binary.BigEndian, &check) != nil {
+		return
+	}
+
+	if ack := int(header.Ack); ack > s.acked {
+		s.acked = ack
+	}
+	for i, in := range inputs {
+		//anything before confirmed is a resend, anything past it means
+		//packets were lost and the gap gets resent
+		if int(header.Start)+i == s.confirmed {
+			s.confirm(in)
+		}
+	}
+
+	if check.Tick >= 0 {
+		if sum, ok := s.checksums[int(check.Tick)]; ok && sum != check.Sum {
+			s.fail(fmt.Errorf("out of sync at tick %d", check.Tick))
+		}
+	}
+}
+
+// Record the remote input for the next unconfirmed tick
+func (s *Session) confirm(in sim.TickInput) {
+	t := s.confirmed
+	remote := 1 - s.local
+	s.inputs[remote] = append(s.inputs[remote], in)
+	s.confirmed++
+	if t < s.tick && s.used[t] != in && (s.rollback < 0 || t < s.rollback) {
+		s.rollback = t
+	}
+}
+
+// Best guess at remote input that hasn't arrived: still steering the same
+// way, and not launching again
+func (s *Session) predict() sim.TickInput {
+	remote := s.inputs[1-s.local]
+	in := remote[len(remote)-1]
+	in.Launch = false
+	return in
+}
+
+// Run one tick of the world, if the remote peer isn't too far behind.
+// local is this peer's input for InputDelay ticks ahead. Returns false
+// when it had to wait, and local was dropped.
+func (s *Session) Advance(w *sim.World, local sim.TickInput) (sim.WorldStatus, bool) {
+	if s.err != nil || !s.started {
+		return sim.WorldRunning, false
+	}
+	if s.tick-s.confirmed >= RollbackWindow {
+		return sim.WorldRunning, false
+	}
+	s.inputs[s.local] = append(s.inputs[s.local], local)
+
+	if s.rollback >= 0 {
+		//effects already played for these ticks, don't repeat them
+		w.Restore(s.snapshots[s.rollback])
+		for t := s.rollback; t < s.tick; t++ {
+			s.step(w, t)
+		}
+		w.TakeEvents()
+		s.rollback = -1
+		s.rollbacks++
+	}
+
+	status := s.step(w, s.tick)
+	s.tick++
+	if status == sim.WorldGameOver && s.tick > s.confirmed {
+		//might be a misprediction, it stays over if it really is
+		status = sim.WorldRunning
+	}
+	s.verify()
+	s.sendInputs()
+	return status, true
+}
+
+func (s *Session) step(w *sim.World, t int) sim.WorldStatus {
+	s.snapshots[t] = w.Snapshot()
+
+	var inputs [2]sim.TickInput
+	remote := 1 - s.local
+	inputs[s.local] = s.inputs[s.local][t]
+	if t < s.confirmed {
+		inputs[remote] = s.inputs[remote][t]
+	} else {
+		inputs[remote] = s.predict()
+	}
+	if t < len(s.used) {
+		s.used[t] = inputs[remote]
+	} else {
+		s.used = append(s.used, inputs[remote])
+	}
+
+	for i, in := range inputs {
+		s.controllers[i].Input = in
+		if in.Launch {
+			w.Launch(i)
+		}
+	}
+	status := w.Update()
+	if status == sim.WorldLevelComplete {
+		//nobody can hold up the other player in a menu, go straight on
+		w.NextLevel()
+		status = sim.WorldRunning
+	}
+	return status
+}
+
+// Checksum ticks whose inputs are all confirmed, and forget what can no
+// longer be rewound to
+func (s *Session) verify() {
+	for ; s.verified < s.confirmed && s.verified+1 < s.tick; s.verified++ {
+		//the world after tick t is the world at the start of t+1
+		s.checksums[s.verified] = s.snapshots[s.verified+1].Checksum()
+		delete(s.checksums, s.verified-ChecksumHistory)
+	}
+	for t := range s.snapshots {
+		if t < s.verified && t < s.confirmed {
+			delete(s.snapshots, t)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package netplay
+
+import (
+	"github.com/CandleEnds/go-breakout/sim"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Relays UDP between a joining peer and the host, holding every packet back
+// and dropping some, like a poor connection
+type lossyLink struct {
+	conn  *net.UDPConn
+	host  *net.UDPAddr
+	delay time.Duration
+	drop  float64
+	rand  *rand.Rand
+
+	mu   sync.Mutex
+	peer *net.UDPAddr
+}
+
+func startLink(t *testing.T, host net.Addr, delay time.Duration, drop float64) *lossyLink {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &lossyLink{conn: conn, host: host.(*net.UDPAddr), delay: delay, drop: drop, rand: rand.New(rand.NewSource(1))}
+	t.Cleanup(func() { conn.Close() })
+	go l.run()
+	return l
+}
+
+func (l *lossyLink) run() {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		to := l.host
+		if from.Port == l.host.Port && from.IP.Equal(l.host.IP) {
+			to = l.peer
+		} else {
+			l.peer = from
+		}
+		lost := l.rand.Float64() < l.drop
+		l.mu.Unlock()
+		if lost || to == nil {
+			continue
+		}
+		data := append([]byte(nil), buf[:n]...)
+		time.AfterFunc(l.delay, func() { l.conn.WriteToUDP(data, to) })
+	}
+}
+
+func (l *lossyLink) Addr() string {
+	return l.conn.LocalAddr().String()
+}
+
+// Host on loopback and join it, through a lossy link unless delay and drop
+// are both 0
+func connect(t *testing.T, mode sim.GameMode, delay time.Duration, drop float64) (*Session, *Session) {
+	t.Helper()
+	host, err := Host("127.0.0.1:0", mode, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(host.Close)
+	addr := host.LocalAddr().String()
+	if delay > 0 || drop > 0 {
+		addr = startLink(t, host.LocalAddr(), delay, drop).Addr()
+	}
+	join, err := Join(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(join.Close)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !host.Started() || !join.Started() {
+		if time.Now().After(deadline) {
+			t.Fatal("peers never connected")
+		}
+		host.Poll()
+		join.Poll()
+		time.Sleep(time.Millisecond)
+	}
+	if join.Mode() != mode || join.Seed() != 7 {
+		t.Fatalf("joining peer was told mode %v seed %d", join.Mode(), join.Seed())
+	}
+	return host, join
+}
+
+// What player plays on tick t: a new direction every 10 ticks, launching
+// now and then. The same every run, so failures can be replayed.
+func scriptedInput(player, t int) sim.TickInput {
+	r := rand.New(rand.NewSource(int64(player*100000 + t/10)))
+	return sim.MakeTickInput(float64(r.Intn(3)-1), t%90 == 0)
+}
+
+// Run both peers until each has simulated ticks ticks, or one fails
+func run(t *testing.T, sessions []*Session, worlds []*sim.World, ticks int) error {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		done := true
+		for i, s := range sessions {
+			s.Poll()
+			if err := s.Err(); err != nil {
+				return err
+			}
+			if s.tick < ticks {
+				done = false
+				s.Advance(worlds[i], scriptedInput(s.Local(), s.tick+InputDelay))
+			}
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out at ticks %d and %d", sessions[0].tick, sessions[1].tick)
+		}
+		time.Sleep(500 * time.Microsecond)
+	}
+}
+
+func makeWorld(s *Session, seed int64) *sim.World {
+	w := sim.MakeWorld(sim.DefaultStageSize, s.Mode())
+	w.SetLevels(sim.GeneratedLevels{Seed: seed})
+	s.Attach(w)
+	return w
+}
+
+func TestRollbackOverLossyLink(t *testing.T) {
+	host, join := connect(t, sim.ModeCoop, 15*time.Millisecond, 0.05)
+	sessions := []*Session{host, join}
+	worlds := []*sim.World{makeWorld(host, host.Seed()), makeWorld(join, join.Seed())}
+	if err := run(t, sessions, worlds, 600); err != nil {
+		t.Fatal(err)
+	}
+
+	if host.Rollbacks()+join.Rollbacks() == 0 {
+		t.Error("late inputs never caused a rollback")
+	}
+	//each peer also compares the other's latest checksum as it plays
+	common := 0
+	for tick, sum := range host.checksums {
+		if other, ok := join.checksums[tick]; ok {
+			common++
+			if sum != other {
+				t.Errorf("tick %d: host checksum %08x, joining peer %08x", tick, sum, other)
+			}
+		}
+	}
+	if common < ChecksumHistory/2 {
+		t.Errorf("only %d ticks checked by both peers", common)
+	}
+	t.Logf("%d and %d rollbacks, %d checksums compared", host.Rollbacks(), join.Rollbacks(), common)
+}
+
+func TestDesyncDetected(t *testing.T) {
+	host, join := connect(t, sim.ModeVersus, 0, 0)
+	sessions := []*Session{host, join}
+	//different levels, as if the simulations had drifted apart
+	worlds := []*sim.World{makeWorld(host, 1), makeWorld(join, 2)}
+	err := run(t, sessions, worlds, 600)
+	if err == nil || !strings.Contains(err.Error(), "out of sync") {
+		t.Fatalf("got %v, want an out of sync error", err)
+	}
+}
+
+func TestPeerLeaving(t *testing.T) {
+	host, join := connect(t, sim.ModeCoop, 0, 0)
+	join.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for host.Err() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("host never noticed the other player leaving")
+		}
+		host.Poll()
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(host.Err().Error(), "left") {
+		t.Errorf("got %v, want the other player left", host.Err())
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var gConfigFile = flag.String("config", "config.json", "file the options are loaded from and saved to")
+
+// Player settings, edited from the options menu and kept in the config file
+type Options struct {
+	ShowFPS   bool `json:"showFPS"`
+	BallTrail bool `json:"ballTrail"`
+	//lighting preset name
+	Lighting string `json:"lighting"`
+	//post-processing passes by name: bloom, vignette, crt
+	PostEffects []string `json:"postEffects"`
+	//colour-blind simulation mode, empty for none
+	ColorBlind string        `json:"colorBlind"`
+	Gamepad    GamepadConfig `json:"gamepad"`
+	//paddle controller: keyboard or mouse
+	Control string `json:"control"`
+	//window widths of mouse travel per lap of the paddle
+	MouseSensitivity float64 `json:"mouseSensitivity"`
+	//key names per action, see InputActions
+	Bindings map[string][]string `json:"bindings"`
+}
+
+var gOptions = Options{
+	ShowFPS:          true,
+	BallTrail:        true,
+	Lighting:         "DAY",
+	PostEffects:      []string{"bloom"},
+	Gamepad:          DefaultGamepadConfig,
+	Control:          "keyboard",
+	MouseSensitivity: 1,
+	Bindings:         DefaultBindings(),
+}
+
+// Read options over the defaults, a missing file leaves them alone
+func LoadOptions(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &gOptions)
+}
+
+func SaveOptions(file string) error {
+	data, err := json.MarshalIndent(gOptions, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// Push the loaded options into the systems that own them
+func (o *Options) Apply() {
+	var errs []error
+	gInput, errs = MakeInputMap(o.Bindings)
+	for _, err := range errs {
+		fmt.Println("bindings:", err)
+	}
+	for _, c := range gInput.Conflicts() {
+		fmt.Println("bindings:", c)
+	}
+
+	for _, p := range LightingPresets {
+		if strings.EqualFold(p.name, o.Lighting) {
+			gLighting.UsePreset(p)
+		}
+	}
+}
+
+func (o *Options) EffectEnabled(name string) bool {
+	for _, e := range o.PostEffects {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) ToggleEffect(name string) {
+	for i, e := range o.PostEffects {
+		if e == name {
+			o.PostEffects = append(o.PostEffects[:i], o.PostEffects[i+1:]...)
+			return
+		}
+	}
+	o.PostEffects = append(o.PostEffects, name)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	mgl32 "github.com/go-gl/mathgl/mgl32"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// Particles alive at once, further spawns are dropped
+const MaxParticles = 2048
+
+// x,y,z,u,v,r,g,b,a
+const particleVertexFloats = 9
+
+type ColorKey struct {
+	t     float64 //0 at birth, 1 at death
+	color mgl.Vec4
+}
+
+// Colour and alpha over a particle's life, keys sorted by t
+type ColorCurve []ColorKey
+
+func (c ColorCurve) At(t float64) mgl.Vec4 {
+	if len(c) == 0 {
+		return mgl.Vec4{1, 1, 1, 1}
+	}
+	if t <= c[0].t {
+		return c[0].color
+	}
+	for i := 1; i < len(c); i++ {
+		if t <= c[i].t {
+			a, b := c[i-1], c[i]
+			f := (t - a.t) / (b.t - a.t)
+			return a.color.Add(b.color.Sub(a.color).Mul(f))
+		}
+	}
+	return c[len(c)-1].color
+}
+
+// How an emitter spawns particles. Jitter values are the maximum random
+// deviation either side of the base value.
+type EmitterConfig struct {
+	//particles per Burst
+	count int
+	//particles per second for continuous emitters
+	rate float64
+
+	lifetime       float64
+	lifetimeJitter float64
+	speed          float64
+	speedJitter    float64
+	//centre direction, particles fan out spread radians either side
+	direction mgl.Vec2
+	spread    float64
+
+	//atlas sprite, sheets play their frames once over each particle's life
+	sprite string
+
+	startSize float64
+	endSize   float64
+	//stage units per second squared, negative pulls down the cylinder
+	gravity float64
+	colors  ColorCurve
+}
+
+var (
+	BlockDebris = EmitterConfig{
+		count:          24,
+		lifetime:       0.8,
+		lifetimeJitter: 0.3,
+		speed:          0.6,
+		speedJitter:    0.4,
+		direction:      mgl.Vec2{0, 1},
+		spread:         math.Pi,
+		sprite:         "dot",
+		startSize:      0.04,
+		endSize:        0.01,
+		gravity:        -2,
+		//tinted with the block colour
+		colors: ColorCurve{
+			{0, mgl.Vec4{1, 1, 1, 1}},
+			{0.6, mgl.Vec4{0.8, 0.8, 0.8, 0.8}},
+			{1, mgl.Vec4{0.4, 0.4, 0.4, 0}},
+		},
+	}
+	PaddleSparks = EmitterConfig{
+		count:          12,
+		lifetime:       0.4,
+		lifetimeJitter: 0.15,
+		speed:          1,
+		speedJitter:    0.5,
+		direction:      mgl.Vec2{0, 1},
+		spread:         math.Pi / 3,
+		sprite:         "spark",
+		startSize:      0.02,
+		endSize:        0.005,
+		gravity:        -3,
+		colors: ColorCurve{
+			{0, mgl.Vec4{1, 1, 0.8, 1}},
+			{0.4, mgl.Vec4{1, 0.7, 0.2, 1}},
+			{1, mgl.Vec4{0.8, 0.2, 0, 0}},
+		},
+	}
+	BallTrail = EmitterConfig{
+		rate:      60,
+		lifetime:  0.35,
+		speed:     0.05,
+		direction: mgl.Vec2{0, 1},
+		spread:    math.Pi,
+		sprite:    "dot",
+		startSize: 0.05,
+		endSize:   0.01,
+		colors: ColorCurve{
+			{0, mgl.Vec4{0.7, 0.85, 1, 0.5}},
+			{1, mgl.Vec4{0.7, 0.85, 1, 0}},
+		},
+	}
+)
+
+type Particle struct {
+	pos      mgl.Vec2
+	velocity mgl.Vec2
+	age      float64
+	lifetime float64
+	config   *EmitterConfig
+	tint     mgl.Vec4
+}
+
+// All live particles share one vertex buffer and go out in a single draw call
+type ParticleSystem struct {
+	particles []Particle
+
+	vao         uint32
+	vbo         uint32
+	indexBuffer uint32
+	program     uint32
+	tex         uint32
+
+	vertices []float32
+	indices  []uint16
+}
+
+func MakeParticleSystem() (*ParticleSystem, error) {
+	program, err := GetParticleShaderProgram()
+	if err != nil {
+		return nil, err
+	}
+	vao, vbo, indexBuffer := makeDynamicVertexArrayObject()
+	return &ParticleSystem{
+		particles:   make([]Particle, 0, MaxParticles),
+		vao:         vao,
+		vbo:         vbo,
+		indexBuffer: indexBuffer,
+		program:     program,
+		tex:         gAtlas.tex,
+	}, nil
+}
+
+// Soft round sprite, opaque in the middle fading out to the edge
+func makeDotImage(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx := (float64(x) + 0.5 - r) / r
+			dy := (float64(y) + 0.5 - r) / r
+			a := 1 - math.Sqrt(dx*dx+dy*dy)
+			if a < 0 {
+				a = 0
+			}
+			i := img.PixOffset(x, y)
+			//premultiplied, as image.RGBA expects
+			v := uint8(a * 255)
+			copy(img.Pix[i:i+4], []uint8{v, v, v, v})
+		}
+	}
+	return img
+}
+
+func jitter(base, amount float64) float64 {
+	return base + (rand.Float64()*2-1)*amount
+}
+
+func (ps *ParticleSystem) spawn(config *EmitterConfig, pos mgl.Vec2, tint mgl.Vec4) {
+	if len(ps.particles) >= MaxParticles {
+		return
+	}
+	angle := math.Atan2(config.direction[1], config.direction[0])
+	angle = jitter(angle, config.spread)
+	speed := jitter(config.speed, config.speedJitter)
+	velocity := mgl.Vec2{math.Cos(angle), math.Sin(angle)}.Mul(speed)
+	lifetime := jitter(config.lifetime, config.lifetimeJitter)
+	ps.particles = append(ps.particles, Particle{pos, velocity, 0, lifetime, config, tint})
+}
+
+// Spawn config.count particles at once, coloured by config.colors * tint
+func (ps *ParticleSystem) Burst(config *EmitterConfig, pos mgl.Vec2, tint mgl.Vec4) {
+	for i := 0; i < config.count; i++ {
+		ps.spawn(config, pos, tint)
+	}
+}
+
+// Age, move and cull particles, dt in seconds
+func (ps *ParticleSystem) Update(dt float64) {
+	live := ps.particles[:0]
+	for _, p := range ps.particles {
+		p.age += dt
+		if p.age >= p.lifetime {
+			continue
+		}
+		p.velocity[1] += p.config.gravity * dt
+		p.pos = p.pos.Add(p.velocity.Mul(dt))
+		live = append(live, p)
+	}
+	ps.particles = live
+}
+
+func (ps *ParticleSystem) Clear() {
+	ps.particles = ps.particles[:0]
+}
+
+// Camera-facing quads in stage space, the shader bends them onto the cylinder
+func (ps *ParticleSystem) Vertexify() {
+	ps.vertices = ps.vertices[:0]
+	ps.indices = ps.indices[:0]
+	for i, p := range ps.particles {
+		t := p.age / p.lifetime
+		c := p.config.colors.At(t)
+		region := FrameAt(gAtlas.Frames(p.config.sprite), t)
+		lU := float32(region[0])
+		hU := float32(region[0] + region[2])
+		lV := float32(region[1])
+		hV := float32(region[1] + region[3])
+		r := float32(c[0] * p.tint[0])
+		g := float32(c[1] * p.tint[1])
+		b := float32(c[2] * p.tint[2])
+		a := float32(c[3] * p.tint[3])
+		half := (p.config.startSize + (p.config.endSize-p.config.startSize)*t) / 2
+		lX := float32(p.pos[0] - half)
+		hX := float32(p.pos[0] + half)
+		lY := float32(p.pos[1] - half)
+		hY := float32(p.pos[1] + half)
+
+		ps.vertices = append(ps.vertices,
+			lX, lY, 0, lU, hV, r, g, b, a,
+			hX, lY, 0, hU, hV, r, g, b, a,
+			lX, hY, 0, lU, lV, r, g, b, a,
+			hX, hY, 0, hU, lV, r, g, b, a,
+		)
+		base := uint16(i * 4)
+		ps.indices = append(ps.indices,
+			base, base+1, base+2,
+			base+1, base+3, base+2,
+		)
+	}
+}
+
+func (ps *ParticleSystem) Draw(VP mgl32.Mat4) {
+	if len(ps.particles) == 0 {
+		return
+	}
+	ps.Vertexify()
+	updateVertexArrayObject(ps.vao, ps.vbo, ps.indexBuffer, ps.vertices, ps.indices)
+
+	// translucent, so test against the world but don't write depth
+	gl.Enable(gl.BLEND)
+	gl.DepthMask(false)
+	defer gl.Disable(gl.BLEND)
+	defer gl.DepthMask(true)
+
+	gl.UseProgram(ps.program)
+
+	vpp := [16]float32(VP)
+	gl.UniformMatrix4fv(glUniformLoc(ps.program, "VP"), 1, false, &vpp[0])
+	setCylinderUniforms(ps.program)
+	gl.Uniform1i(glUniformLoc(ps.program, "Sampler"), 0)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, ps.tex)
+
+	stride := int32(particleVertexFloats * 4)
+	bindAttrib(ps.program, "position", 3, stride, 0)
+	bindAttrib(ps.program, "texCoord", 2, stride, 3*4)
+	bindAttrib(ps.program, "color", 4, stride, 5*4)
+
+	gl.DrawElements(gl.TRIANGLES, int32(len(ps.indices)), gl.UNSIGNED_SHORT, nil)
+}
+
+// Continuous source, e.g. the ball trail. Fractional particles carry over
+// between updates so low rates still emit evenly.
+type Emitter struct {
+	config *EmitterConfig
+	owed   float64
+}
+
+func (e *Emitter) Emit(ps *ParticleSystem, pos mgl.Vec2, dt float64) {
+	e.owed += e.config.rate * dt
+	for e.owed >= 1 {
+		ps.spawn(e.config, pos, mgl.Vec4{1, 1, 1, 1})
+		e.owed--
+	}
+}
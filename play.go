@@ -0,0 +1,392 @@
+package main
+
+import (
+	"github.com/CandleEnds/go-breakout/netplay"
+	"github.com/CandleEnds/go-breakout/remote"
+	"github.com/CandleEnds/go-breakout/sim"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"strings"
+	"time"
+)
+
+// Destroyed block still fading out
+type FadingBlock struct {
+	block *sim.Block
+	age   float64
+}
+
+type PlayingState struct {
+	world     *sim.World
+	view      *WorldView
+	particles *ParticleSystem
+	//one per player
+	trails []Emitter
+	fading []FadingBlock
+	//gOptions.Control the paddle controller was made for
+	control string
+	//nil for a local game
+	net *netplay.Session
+	//nil unless playing on a dedicated server
+	server *remote.Client
+	//status of the server's latest state
+	serverStatus sim.WorldStatus
+	//devices feeding this machine's player in network and server games,
+	//sampled into a TickInput each tick
+	local PaddleController
+	//launch pressed since the last sample
+	launch bool
+	//bots playing by themselves until a key is pressed
+	demo bool
+	//trying out a level from the editor, which it goes back to
+	test bool
+}
+
+func MakePlayingState(stageSize mgl.Vec2, mode sim.GameMode) (*PlayingState, error) {
+	levels, err := LocalLevels()
+	if err != nil {
+		return nil, err
+	}
+	world := sim.MakeWorld(stageSize, mode)
+	world.SetLevels(levels)
+	for i, player := range world.Players {
+		player.Paddle.SetController(MakeController(gOptions.Control, i))
+	}
+	particles, err := MakeParticleSystem()
+	if err != nil {
+		return nil, err
+	}
+	trails := make([]Emitter, len(world.Players))
+	for i := range trails {
+		trails[i].config = &BallTrail
+	}
+	return &PlayingState{
+		world:     world,
+		view:      MakeWorldView(),
+		particles: particles,
+		trails:    trails,
+		control:   gOptions.Control,
+	}, nil
+}
+
+// Network game, with the session driving the paddles
+func MakeNetPlayingState(stageSize mgl.Vec2, session *netplay.Session) (*PlayingState, error) {
+	p, err := MakePlayingState(stageSize, session.Mode())
+	if err != nil {
+		return nil, err
+	}
+	//both peers have to play the same levels
+	p.world.SetLevels(SeedLevels(session.Seed()))
+	session.Attach(p.world)
+	p.net = session
+	p.local = MakeController(gOptions.Control, 0)
+	return p, nil
+}
+
+// Game on a dedicated server, which runs the world and sends it back each
+// tick
+func MakeServerPlayingState(stageSize mgl.Vec2, client *remote.Client) (*PlayingState, error) {
+	p, err := MakePlayingState(stageSize, client.Mode)
+	if err != nil {
+		return nil, err
+	}
+	//nothing to show until the server's first state
+	p.world.Blocks = nil
+	p.server = client
+	p.local = MakeController(gOptions.Control, 0)
+	return p, nil
+}
+
+// Bots playing each other behind the title, until a key is pressed
+func MakeDemoState(stageSize mgl.Vec2) (*PlayingState, error) {
+	p, err := MakePlayingState(stageSize, sim.ModeCoop)
+	if err != nil {
+		return nil, err
+	}
+	//good enough to keep a rally going, bad enough to lose eventually
+	skill := sim.BotSkills[1]
+	seed := time.Now().UnixNano()
+	for i, player := range p.world.Players {
+		player.Paddle.SetController(BotController{sim.MakeBot(p.world, i, skill, seed+int64(i))})
+	}
+	p.demo = true
+	return p, nil
+}
+
+// One player on one level from the editor, until the level is cleared, the
+// game is lost or pause is pressed
+func MakeTestPlayState(stageSize mgl.Vec2, level *sim.Level) (*PlayingState, error) {
+	p, err := MakePlayingState(stageSize, sim.ModeSingle)
+	if err != nil {
+		return nil, err
+	}
+	p.world.SetLevels(sim.LevelList{level})
+	p.test = true
+	return p, nil
+}
+
+// Game where the blocks keep coming down, see sim.Pressure
+func MakePressureState(stageSize mgl.Vec2, mode sim.GameMode) (*PlayingState, error) {
+	pressure, err := LocalPressure()
+	if err != nil {
+		return nil, err
+	}
+	p, err := MakePlayingState(stageSize, mode)
+	if err != nil {
+		return nil, err
+	}
+	p.world.SetPressure(pressure)
+	return p, nil
+}
+
+// New game of the same kind as world
+func MakeReplayState(world *sim.World) (*PlayingState, error) {
+	if world.Pressure != nil {
+		return MakePressureState(world.StageSize, world.Mode)
+	}
+	return MakePlayingState(world.StageSize, world.Mode)
+}
+
+// Paddle this machine drives in a network or server game, -1 for a
+// spectator
+func (p *PlayingState) localPlayer() int {
+	if p.net != nil {
+		return p.net.Local()
+	}
+	return p.server.Player
+}
+
+func (p *PlayingState) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	if p.demo {
+		//back to the title it was started from
+		if action == glfw.Press {
+			gStates.Pop()
+		}
+		return true
+	}
+	if p.local != nil {
+		return p.handleNetKey(key, scancode, action, mods)
+	}
+	for _, player := range p.world.Players {
+		paddle := player.Paddle
+		c, ok := paddle.GetController().(PaddleController)
+		if ok && c.HandleKey(paddle, key, scancode, action, mods) {
+			return true
+		}
+	}
+
+	if action == glfw.Press && gInput.Is(ActionPause, key, mods) {
+		if p.test {
+			//straight back to the editor
+			gStates.Pop()
+			return true
+		}
+		p.Suspend(MakePauseState(p.world))
+		return true
+	}
+	if action == glfw.Press && gInput.Is(ActionLaunch, key, mods) {
+		p.world.Launch(0)
+		return true
+	}
+	if action == glfw.Press && gInput.Is(ActionP2Launch, key, mods) {
+		p.world.Launch(1)
+		return true
+	}
+	return false
+}
+
+// Everyone plays with the first player's keys in network and server games
+func (p *PlayingState) handleNetKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	player := p.localPlayer()
+	if player >= 0 && p.local.HandleKey(p.world.Players[player].Paddle, key, scancode, action, mods) {
+		return true
+	}
+	if action == glfw.Press && gInput.Is(ActionPause, key, mods) {
+		//in a network game the other player waits until this one resumes,
+		//a server carries on regardless
+		p.Suspend(MakePauseState(p.world))
+		return true
+	}
+	if action == glfw.Press && gInput.Is(ActionLaunch, key, mods) && player >= 0 {
+		p.launch = true
+		return true
+	}
+	return false
+}
+
+// This machine's input for the next tick, from the same devices as the
+// first player of a local game
+func (p *PlayingState) sample(player int) sim.TickInput {
+	//controllers steer a paddle, so let them steer a copy
+	probe := *p.world.Players[player].Paddle
+	probe.Stop()
+	p.local.Update(&probe, p.world.StageSize)
+	steer := probe.Steering() + gGamepad.Stick()
+	return sim.MakeTickInput(steer, p.launch)
+}
+
+// Push an overlay that freezes the world, dropping any movement queued up
+// for the next tick
+func (p *PlayingState) Suspend(overlay GameState) {
+	for _, player := range p.world.Players {
+		player.Paddle.Stop()
+	}
+	gStates.Push(overlay)
+}
+
+func (p *PlayingState) Update() {
+	p.syncController()
+	var status sim.WorldStatus
+	switch {
+	case p.net != nil:
+		if err := p.net.Err(); err != nil {
+			QuitToTitle(p.world.StageSize).menu.ShowError("CONNECTION LOST", err)
+			return
+		}
+		var ok bool
+		if status, ok = p.net.Advance(p.world, p.sample(p.net.Local())); !ok {
+			return
+		}
+		p.launch = false
+	case p.server != nil:
+		err := p.server.Err()
+		if player := p.server.Player; player >= 0 && err == nil {
+			err = p.server.SendInput(p.sample(player))
+			p.launch = false
+		}
+		if err != nil {
+			QuitToTitle(p.world.StageSize).menu.ShowError("CONNECTION LOST", err)
+			return
+		}
+		if state := p.server.Latest(); state != nil && state.World != p.world {
+			p.takeServerWorld(state.World)
+			p.serverStatus = state.Status
+		}
+	case p.demo:
+		status = p.world.Update()
+	default:
+		//the gamepad always drives the first player
+		p.world.Players[0].Paddle.Steer(gGamepad.Stick())
+		status = p.world.Update()
+	}
+	p.view.Update(p.world)
+	p.updateEffects()
+	if p.server != nil {
+		//the server moves on by itself, levels and game over included
+		return
+	}
+
+	if p.demo {
+		switch status {
+		case sim.WorldLevelComplete:
+			p.world.NextLevel()
+		case sim.WorldGameOver:
+			gStates.Pop()
+		}
+		return
+	}
+	if p.test {
+		//cleared or lost, either way back to editing
+		if status != sim.WorldRunning {
+			gStates.Pop()
+		}
+		return
+	}
+	switch status {
+	case sim.WorldLevelComplete:
+		p.Suspend(MakeLevelCompleteState(p.world))
+	case sim.WorldGameOver:
+		//a network game can't be replayed without both players agreeing.
+		//The session stays open until leaving the menu, so the other peer
+		//gets the inputs it needs to reach game over too.
+		p.Suspend(MakeGameOverState(p.world, p.net == nil))
+	}
+}
+
+// Pick up a control change made from the pause menu
+func (p *PlayingState) syncController() {
+	if gOptions.Control != p.control {
+		p.control = gOptions.Control
+		if p.local != nil {
+			p.local = MakeController(p.control, 0)
+		} else {
+			p.world.Players[0].Paddle.SetController(MakeController(p.control, 0))
+		}
+	}
+}
+
+// The server only sends whole worlds, so destroyed blocks are spotted by
+// what's missing from the last one
+func (p *PlayingState) takeServerWorld(next *sim.World) {
+	if next.Level == p.world.Level {
+		alive := make(map[int]bool, len(next.Blocks))
+		for _, b := range next.Blocks {
+			alive[b.ID] = true
+		}
+		for _, b := range p.world.Blocks {
+			if !alive[b.ID] {
+				p.particles.Burst(&BlockDebris, b.Center(), b.Color)
+				p.fading = append(p.fading, FadingBlock{b, 0})
+			}
+		}
+	}
+	p.world = next
+}
+
+// Particles run on the simulation tick, so they freeze along with the world
+func (p *PlayingState) updateEffects() {
+	dt := TimePerUpdate.Seconds()
+	for _, e := range p.world.TakeEvents() {
+		switch e.Kind {
+		case sim.EventBlockDestroyed:
+			p.particles.Burst(&BlockDebris, e.Pos, e.Color)
+			p.fading = append(p.fading, FadingBlock{e.Block, 0})
+		case sim.EventPaddleHit:
+			p.particles.Burst(&PaddleSparks, e.Pos, e.Color)
+		}
+	}
+	if gOptions.BallTrail {
+		for i, player := range p.world.Players {
+			if !player.Out() {
+				p.trails[i].Emit(p.particles, player.Ball.Center(), dt)
+			}
+		}
+	}
+	p.particles.Update(dt)
+
+	fading := p.fading[:0]
+	for _, f := range p.fading {
+		f.age += dt
+		if f.age < BlockFadeTime {
+			fading = append(fading, f)
+		}
+	}
+	p.fading = fading
+}
+
+func (p *PlayingState) Draw(elapsed time.Duration) {
+	VP := p.view.Draw(p.world)
+	for _, f := range p.fading {
+		p.view.DrawFading(f.block, VP, f.age/BlockFadeTime)
+	}
+	p.particles.Draw(VP)
+	gHUD.DrawStats(p.world)
+	if p.demo {
+		gHUD.Begin()
+		gHUD.Label("DEMO - PRESS ANY KEY", AnchorBottom, HUDWhite)
+		gHUD.End()
+	}
+	if p.test {
+		gHUD.Begin()
+		pause := strings.ToUpper(gInput.Describe(ActionPause))
+		gHUD.Label("TEST PLAY - "+pause+" TO EDIT", AnchorBottom, HUDWhite)
+		gHUD.End()
+	}
+	if p.server != nil && p.serverStatus == sim.WorldGameOver {
+		gHUD.DrawNotice("GAME OVER", ScoreMessage(p.world))
+	}
+}
+
+func (p *PlayingState) IsOverlay() bool {
+	return false
+}
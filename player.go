@@ -1,111 +1,57 @@
 package main
 
 import (
+	"github.com/CandleEnds/go-breakout/sim"
 	//"fmt"
 	glfw "github.com/go-gl/glfw3/v3.2/glfw"
-	mgl32 "github.com/go-gl/mathgl/mgl32"
 	mgl "github.com/go-gl/mathgl/mgl64"
 )
 
-type KeyHandleFunc func(*Paddle, glfw.Key, int, glfw.Action, glfw.ModifierKey) bool
-
-func PaddleHandleKey(paddle *Paddle,
-	key glfw.Key,
-	scancode int,
-	action glfw.Action,
-	mods glfw.ModifierKey) bool {
-	//direction of key movement, 1 is press, -1 is release
-	var d int
-	if action == glfw.Press {
-		d = 1
-	} else if action == glfw.Release {
-		d = -1
-	} else {
-		return false
-	}
-
-	if key == glfw.KeyLeft {
-		paddle.Move(-d)
-	} else if key == glfw.KeyRight {
-		paddle.Move(d)
-	} else {
-		return false
-	}
-
-	return true
-}
-
-type Paddle struct {
-	renderer   *RenderComponent
-	controller KeyHandleFunc
-	pos        mgl.Vec2
-	speed      float64
-	velocity   int
-	size       mgl.Vec2
-}
-
-func MakePaddle(width float64, sceneSize mgl.Vec2) *Paddle {
-	size := mgl.Vec2{width, 0.15}
-	renderComp := MakeRenderRect(size, 0, "./greenblock.png")
-	pos := mgl.Vec2{(sceneSize[0] - width) / 2, 0.05 * sceneSize[1]}
-	speed := 1 * TimePerUpdate.Seconds()
-	return &Paddle{renderComp, PaddleHandleKey, pos, float64(speed), 0, size}
-}
-
-func (p *Paddle) Draw(VP mgl32.Mat4) {
-	p.renderer.Draw(p.pos, VP)
+// Drives a paddle from an input device
+type PaddleController interface {
+	//once per tick, before the paddle moves
+	sim.Controller
+	HandleKey(p *sim.Paddle, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool
 }
 
-func (p *Paddle) GetController() KeyHandleFunc {
-	return p.controller
+// Controller for a "control" option value and player index. Only the first
+// player can use the mouse, anything else gets the keyboard.
+func MakeController(control string, player int) PaddleController {
+	keys := KeyboardController{PlayerInputActions[player]}
+	if control == "mouse" && player == 0 {
+		return &MouseController{KeyboardController: keys}
+	}
+	return keys
 }
 
-func (p *Paddle) GetPos() mgl.Vec2 {
-	return p.pos
+// Moves the paddle while the player's move keys are held. Direction is read
+// from gKeys every tick, so a lost release event can't leave it drifting.
+type KeyboardController struct {
+	actions PlayerActions
 }
 
-func (p *Paddle) GetSize() mgl.Vec2 {
-	return p.size
+func (k KeyboardController) HandleKey(p *sim.Paddle, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	//claim the keys so they don't also move the camera
+	return gInput.Is(k.actions.left, key, mods) || gInput.Is(k.actions.right, key, mods)
 }
 
-func (p *Paddle) Update(stageSize mgl.Vec2) {
-	p.pos[0] += p.speed * float64(p.velocity)
-	if p.pos[0] > stageSize[0] {
-		p.pos[0] -= stageSize[0]
-		//p.pos[0] = stageSize[0] - p.size[0]
-	} else if p.pos[0] < 0 {
-		p.pos[0] += stageSize[0]
-		//p.pos[0] = 0
+func (k KeyboardController) Update(p *sim.Paddle, stageSize mgl.Vec2) {
+	//both held cancel out
+	var dir float64
+	if gInput.Held(k.actions.left, &gKeys) {
+		dir--
 	}
-
-	//fmt.Println(p.pos[0])
-}
-
-//negative is left, positive is right
-func (p *Paddle) Move(dir int) {
-	p.velocity += dir
+	if gInput.Held(k.actions.right, &gKeys) {
+		dir++
+	}
+	p.Steer(dir)
 }
 
-func (p *Paddle) Collided(c Collider, overlap Rect) {
-	/*
-		impulse := mgl.Vec2{0, 0}
-		if overlap.Height() > overlap.Width() {
-			impulse[0] = 1
-		} else {
-			center := overlap.Center()[0]
-			padcenter := p.pos[0] + p.size[0]/2
-			norm := (center - padcenter) / p.size[0] * 2
-			impulse[0] = norm
-		}
-		fmt.Println(impulse[0])
-		c.Impulse(impulse)
-	*/
+// Lets a sim.Bot take a paddle in the game, e.g. for the attract mode demo
+type BotController struct {
+	*sim.Bot
 }
 
-func (p *Paddle) ResolveCollision(pv []mgl.Vec2) {
-
-}
-
-func (p *Paddle) Impulse(v mgl.Vec2) {
-
+func (b BotController) HandleKey(p *sim.Paddle, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	return false
 }
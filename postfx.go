@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Offscreen colour buffer, with depth if the scene is drawn into it
+type RenderTarget struct {
+	fbo    uint32
+	tex    uint32
+	depth  uint32
+	width  int
+	height int
+}
+
+func MakeRenderTarget(width, height int, withDepth bool) (*RenderTarget, error) {
+	t := &RenderTarget{width: width, height: height}
+
+	gl.GenTextures(1, &t.tex)
+	gl.BindTexture(gl.TEXTURE_2D, t.tex)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(width), int32(height), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	gl.GenFramebuffers(1, &t.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t.tex, 0)
+
+	if withDepth {
+		gl.GenRenderbuffers(1, &t.depth)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, t.depth)
+		gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, int32(width), int32(height))
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, t.depth)
+	}
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if err := checkGLerror("create render target"); err != nil {
+		t.Delete()
+		return nil, err
+	}
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		t.Delete()
+		return nil, fmt.Errorf("framebuffer %dx%d incomplete: 0x%x", width, height, status)
+	}
+	return t, nil
+}
+
+func (t *RenderTarget) Delete() {
+	gl.DeleteFramebuffers(1, &t.fbo)
+	gl.DeleteTextures(1, &t.tex)
+	if t.depth != 0 {
+		gl.DeleteRenderbuffers(1, &t.depth)
+	}
+}
+
+// Bind for drawing, nil is the window's framebuffer
+func (t *RenderTarget) Bind() {
+	if t == nil {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		SetViewport(gScreen.fbWidth, gScreen.fbHeight)
+		return
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	SetViewport(t.width, t.height)
+}
+
+// One full-screen step of the chain. Passes read src and write dst, where a
+// nil dst is the screen.
+type PostPass interface {
+	// config and menu name
+	Name() string
+	Apply(chain *PostChain, src, dst *RenderTarget)
+}
+
+// Pass made of a single fragment shader over the scene texture
+type ShaderPass struct {
+	name string
+	//fragment shader name, see shaderFile
+	frag    string
+	program uint32
+	//set when the shader failed to build, the pass is then skipped
+	err error
+	//extra uniforms, may be nil
+	uniforms func(program uint32)
+}
+
+func (p *ShaderPass) Name() string {
+	return p.name
+}
+
+func (p *ShaderPass) Apply(chain *PostChain, src, dst *RenderTarget) {
+	if p.program == 0 && p.err == nil {
+		p.program, p.err = loadProgram("vert_post", p.frag)
+		if p.err != nil {
+			fmt.Println(p.name, "disabled:", p.err)
+		}
+	}
+	if p.err != nil {
+		chain.Run(chain.copy, src, dst, nil)
+		return
+	}
+	chain.Run(p.program, src, dst, p.uniforms)
+}
+
+// Blur the bright parts of the scene at half resolution and add them back
+type BloomPass struct {
+	threshold float64
+	intensity float64
+	//blur iterations, each one horizontal + vertical
+	passes int
+
+	bright  uint32
+	blur    uint32
+	combine uint32
+	ping    *RenderTarget
+	pong    *RenderTarget
+	//set when a shader failed to build, the pass is then skipped
+	err error
+}
+
+func (p *BloomPass) Name() string {
+	return "bloom"
+}
+
+func (p *BloomPass) resize(width, height int) error {
+	if p.ping != nil && p.ping.width == width && p.ping.height == height {
+		return nil
+	}
+	p.release()
+	var err error
+	if p.ping, err = MakeRenderTarget(width, height, false); err != nil {
+		return err
+	}
+	p.pong, err = MakeRenderTarget(width, height, false)
+	return err
+}
+
+func (p *BloomPass) release() {
+	if p.ping != nil {
+		p.ping.Delete()
+		p.ping = nil
+	}
+	if p.pong != nil {
+		p.pong.Delete()
+		p.pong = nil
+	}
+}
+
+func (p *BloomPass) load() error {
+	var err error
+	if p.bright, err = loadProgram("vert_post", "frag_post_bright"); err != nil {
+		return err
+	}
+	if p.blur, err = loadProgram("vert_post", "frag_post_blur"); err != nil {
+		return err
+	}
+	p.combine, err = loadProgram("vert_post", "frag_post_bloom")
+	return err
+}
+
+func (p *BloomPass) Apply(chain *PostChain, src, dst *RenderTarget) {
+	if p.combine == 0 && p.err == nil {
+		p.err = p.load()
+		if p.err != nil {
+			fmt.Println("bloom disabled:", p.err)
+		}
+	}
+	err := p.err
+	if err == nil {
+		err = p.resize(src.width/2, src.height/2)
+	}
+	if err != nil {
+		//no bloom is better than no picture
+		chain.Run(chain.copy, src, dst, nil)
+		return
+	}
+
+	chain.Run(p.bright, src, p.ping, func(program uint32) {
+		gl.Uniform1f(glUniformLoc(program, "threshold"), float32(p.threshold))
+	})
+	for i := 0; i < p.passes; i++ {
+		chain.Run(p.blur, p.ping, p.pong, func(program uint32) {
+			gl.Uniform2f(glUniformLoc(program, "direction"), 1, 0)
+		})
+		chain.Run(p.blur, p.pong, p.ping, func(program uint32) {
+			gl.Uniform2f(glUniformLoc(program, "direction"), 0, 1)
+		})
+	}
+	chain.Run(p.combine, src, dst, func(program uint32) {
+		gl.ActiveTexture(gl.TEXTURE1)
+		gl.BindTexture(gl.TEXTURE_2D, p.ping.tex)
+		gl.Uniform1i(glUniformLoc(program, "bloom"), 1)
+		gl.Uniform1f(glUniformLoc(program, "intensity"), float32(p.intensity))
+		gl.ActiveTexture(gl.TEXTURE0)
+	})
+}
+
+// Colour vision deficiency simulations (Machado et al. 2009, full
+// severity), row major
+var ColorBlindModes = []struct {
+	name   string
+	matrix [9]float32
+}{
+	{"protanopia", [9]float32{
+		0.152286, 1.052583, -0.204868,
+		0.114503, 0.786281, 0.099216,
+		-0.003882, -0.048116, 1.051998,
+	}},
+	{"deuteranopia", [9]float32{
+		0.367322, 0.860646, -0.227968,
+		0.280085, 0.672501, 0.047413,
+		-0.011820, 0.042940, 0.968881,
+	}},
+	{"tritanopia", [9]float32{
+		1.255528, -0.076749, -0.178779,
+		-0.078411, 0.930809, 0.147602,
+		0.004733, 0.691367, 0.303900,
+	}},
+}
+
+func colorBlindMatrix(name string) (*[9]float32, bool) {
+	for i := range ColorBlindModes {
+		if ColorBlindModes[i].name == name {
+			return &ColorBlindModes[i].matrix, true
+		}
+	}
+	return nil, false
+}
+
+// The scene is drawn into an offscreen target between Begin and End, then
+// every enabled pass runs in order with the last one drawing to the screen.
+// With nothing enabled it stays out of the way and draws straight to the
+// window.
+type PostChain struct {
+	passes []PostPass
+	scene  *RenderTarget
+	ping   *RenderTarget
+	pong   *RenderTarget
+	copy   uint32
+	quad   uint32
+	//the quad's vertices, bound again for each pass
+	quadVBO uint32
+	//Begin redirected drawing offscreen
+	active bool
+}
+
+var gPost *PostChain
+
+func MakePostChain() (*PostChain, error) {
+	if !gGLCaps.framebuffers {
+		return nil, fmt.Errorf("GL %s driver has no framebuffer objects", gGLProfile.name)
+	}
+	c := &PostChain{}
+	c.passes = []PostPass{
+		&BloomPass{threshold: 0.75, intensity: 0.9, passes: 2},
+		&ShaderPass{name: "vignette", frag: "frag_post_vignette",
+			uniforms: func(program uint32) {
+				gl.Uniform1f(glUniformLoc(program, "strength"), 0.45)
+			}},
+		&ShaderPass{name: "crt", frag: "frag_post_crt",
+			uniforms: func(program uint32) {
+				gl.Uniform1f(glUniformLoc(program, "curvature"), 0.06)
+				gl.Uniform1f(glUniformLoc(program, "scanlines"), 0.25)
+			}},
+		&ShaderPass{name: "colorblind", frag: "frag_post_colorblind",
+			uniforms: func(program uint32) {
+				m, _ := colorBlindMatrix(gOptions.ColorBlind)
+				gl.UniformMatrix3fv(glUniformLoc(program, "simulation"), 1, true, &m[0])
+			}},
+	}
+	var err error
+	if c.copy, err = loadProgram("vert_post", "frag_post_copy"); err != nil {
+		return nil, err
+	}
+
+	//two triangles covering clip space
+	quad := []float32{-1, -1, 1, -1, -1, 1, 1, -1, 1, 1, -1, 1}
+	c.quad = genVertexArray()
+	bindVertexArray(c.quad)
+	gl.GenBuffers(1, &c.quadVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, c.quadVBO)
+	gl.BufferData(gl.ARRAY_BUFFER, len(quad)*4, gl.Ptr(quad), gl.STATIC_DRAW)
+	if err := checkGLerror("create post-processing quad"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *PostChain) enabled() []PostPass {
+	var passes []PostPass
+	for _, p := range c.passes {
+		if p.Name() == "colorblind" {
+			if _, ok := colorBlindMatrix(gOptions.ColorBlind); ok {
+				passes = append(passes, p)
+			}
+		} else if gOptions.EffectEnabled(p.Name()) {
+			passes = append(passes, p)
+		}
+	}
+	return passes
+}
+
+func (c *PostChain) resize(width, height int) error {
+	if c.scene != nil && c.scene.width == width && c.scene.height == height {
+		return nil
+	}
+	c.release()
+	var err error
+	if c.scene, err = MakeRenderTarget(width, height, true); err != nil {
+		return err
+	}
+	if c.ping, err = MakeRenderTarget(width, height, false); err != nil {
+		return err
+	}
+	c.pong, err = MakeRenderTarget(width, height, false)
+	return err
+}
+
+func (c *PostChain) release() {
+	for _, t := range []**RenderTarget{&c.scene, &c.ping, &c.pong} {
+		if *t != nil {
+			(*t).Delete()
+			*t = nil
+		}
+	}
+}
+
+// Call before drawing the frame
+func (c *PostChain) Begin() {
+	c.active = false
+	if len(c.enabled()) == 0 {
+		return
+	}
+	if err := c.resize(gScreen.fbWidth, gScreen.fbHeight); err != nil {
+		fmt.Println("post-processing disabled:", err)
+		c.release()
+		return
+	}
+	c.scene.Bind()
+	c.active = true
+}
+
+// Call after drawing the frame, before swapping buffers
+func (c *PostChain) End() {
+	if !c.active {
+		return
+	}
+	c.active = false
+
+	BeginOverlay()
+	defer EndOverlay()
+
+	passes := c.enabled()
+	src := c.scene
+	targets := []*RenderTarget{c.ping, c.pong}
+	for i, p := range passes {
+		var dst *RenderTarget
+		if i < len(passes)-1 {
+			dst = targets[i%2]
+		}
+		p.Apply(c, src, dst)
+		src = dst
+	}
+}
+
+// Draw a full-screen quad with program, reading src as "scene"
+func (c *PostChain) Run(program uint32, src, dst *RenderTarget, uniforms func(program uint32)) {
+	dst.Bind()
+	gl.UseProgram(program)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, src.tex)
+	gl.Uniform1i(glUniformLoc(program, "scene"), 0)
+	gl.Uniform2f(glUniformLoc(program, "resolution"), float32(src.width), float32(src.height))
+	if uniforms != nil {
+		uniforms(program)
+	}
+
+	bindVertexArray(c.quad)
+	gl.BindBuffer(gl.ARRAY_BUFFER, c.quadVBO)
+	bindAttrib(program, "position", 2, 2*4, 0)
+	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+}
@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	"net"
+	"sync"
+	"time"
+)
+
+// Give up on a server that doesn't answer a join in this long
+const DialTimeout = 5 * time.Second
+
+// A connection to a Server, as a player or a spectator
+type Client struct {
+	conn net.Conn
+	//paddle this client drives, -1 when spectating
+	Player int
+	Mode   sim.GameMode
+
+	mu     sync.Mutex
+	latest *State
+	err    error
+	//bytes of state received, for measuring the delta compression
+	received int
+}
+
+func Dial(addr string, spectate bool) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteMessage(conn, MsgJoin, JoinRequest{ProtocolVersion, spectate}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(DialTimeout))
+	kind, payload, err := ReadMessage(conn)
+	conn.SetReadDeadline(time.Time{})
+	var welcome Welcome
+	if err == nil {
+		switch {
+		case kind == MsgError:
+			err = fmt.Errorf("server refused: %s", payload)
+		case kind != MsgWelcome || Decode(payload, &welcome) != nil:
+			err = errors.New("server sent something other than a welcome")
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &Client{conn: conn, Player: int(welcome.Player), Mode: sim.GameMode(welcome.Mode)}
+	go c.receiveLoop()
+	return c, nil
+}
+
+func (c *Client) receiveLoop() {
+	var prev []byte
+	for {
+		kind, payload, err := ReadMessage(c.conn)
+		var state *State
+		if err == nil {
+			switch kind {
+			case MsgError:
+				err = fmt.Errorf("server: %s", payload)
+			case MsgState:
+				var next []byte
+				if next, err = ApplyDelta(prev, payload); err == nil {
+					state, err = DecodeState(next)
+					prev = next
+				}
+			default:
+				continue
+			}
+		}
+		c.mu.Lock()
+		if err != nil {
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+			return
+		}
+		c.latest = state
+		c.received += len(payload)
+		c.mu.Unlock()
+	}
+}
+
+// Send this tick's input, spectators' inputs are ignored by the server
+func (c *Client) SendInput(in sim.TickInput) error {
+	return WriteMessage(c.conn, MsgInput, in)
+}
+
+// Newest state from the server, nil until the first arrives
+func (c *Client) Latest() *State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latest
+}
+
+// Total bytes of state received so far
+func (c *Client) Received() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.received
+}
+
+// Why the connection dropped, nil while it's up
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Safe to call on a nil Client
+func (c *Client) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = errors.New("connection closed")
+	}
+	c.mu.Unlock()
+	c.conn.Close()
+}
@@ -0,0 +1,94 @@
+// Playing and watching games run by a server. The server owns the only
+// real simulation; clients send it their inputs and get back the state of
+// the world, which is all they draw.
+package remote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Bump when any message changes
+const ProtocolVersion = 3
+
+// Largest message either side accepts
+const MaxMessageSize = 1 << 20
+
+type MessageKind uint8
+
+const (
+	// client to server, first message: JoinRequest
+	MsgJoin MessageKind = iota + 1
+	// server to client, answers MsgJoin: Welcome
+	MsgWelcome
+	// client to server, once per tick: sim.TickInput
+	MsgInput
+	// server to client, once per tick: Delta of EncodeState against the
+	// previous state sent to that client
+	MsgState
+	// server to client before hanging up: the reason, as text
+	MsgError
+)
+
+type messageHeader struct {
+	Kind   MessageKind
+	Length uint32
+}
+
+type JoinRequest struct {
+	Version uint8
+	//watch only, even if there's a free paddle
+	Spectate bool
+}
+
+type Welcome struct {
+	//paddle this client drives, -1 for spectators
+	Player int8
+	Mode   uint8
+}
+
+// Send one message, body is encoded with encoding/binary unless it's
+// already bytes
+func WriteMessage(w io.Writer, kind MessageKind, body interface{}) error {
+	var payload []byte
+	switch b := body.(type) {
+	case []byte:
+		payload = b
+	case string:
+		payload = []byte(b)
+	default:
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, binary.BigEndian, body); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+	}
+	//one write per message, so concurrent writers can't interleave
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, messageHeader{kind, uint32(len(payload))})
+	buf.Write(payload)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func ReadMessage(r io.Reader) (MessageKind, []byte, error) {
+	var header messageHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return 0, nil, err
+	}
+	if header.Length > MaxMessageSize {
+		return 0, nil, fmt.Errorf("message of %d bytes is too big", header.Length)
+	}
+	payload := make([]byte, header.Length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header.Kind, payload, nil
+}
+
+// Decode a message payload into a fixed size value
+func Decode(payload []byte, v interface{}) error {
+	return binary.Read(bytes.NewReader(payload), binary.BigEndian, v)
+}
@@ -0,0 +1,265 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"net"
+	"sync"
+	"time"
+)
+
+// Ticks the final state stays up before a new game starts
+const GameOverTicks = 5 * 60
+
+// Runs one game at a time for whoever connects. Players take the free
+// paddles in order, everyone else spectates. When a game ends a new one
+// starts, so spectators can come and go at any point.
+type Server struct {
+	listener  net.Listener
+	mode      sim.GameMode
+	stageSize mgl.Vec2
+	levels    sim.LevelSource
+
+	mu      sync.Mutex
+	world   *sim.World
+	tick    uint32
+	status  sim.WorldStatus
+	clients map[*serverClient]bool
+	//client driving each paddle, nil while it's free
+	players     []*serverClient
+	controllers []*sim.InputController
+	//launch pressed since the last tick, per paddle
+	launches []bool
+	//ticks since the game ended
+	overFor int
+	closed  bool
+}
+
+type serverClient struct {
+	conn   net.Conn
+	player int
+	//newest encoded state, the writer skips any it didn't get to
+	latest chan []byte
+	done   chan struct{}
+}
+
+func Listen(addr string, mode sim.GameMode, stageSize mgl.Vec2, levels sim.LevelSource) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener:  listener,
+		mode:      mode,
+		stageSize: stageSize,
+		levels:    levels,
+		clients:   make(map[*serverClient]bool),
+	}
+	s.newGame()
+	return s, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) newGame() {
+	s.world = sim.MakeWorld(s.stageSize, s.mode)
+	s.world.SetLevels(s.levels)
+	n := len(s.world.Players)
+	if s.players == nil {
+		s.players = make([]*serverClient, n)
+	}
+	s.controllers = make([]*sim.InputController, n)
+	s.launches = make([]bool, n)
+	for i, p := range s.world.Players {
+		s.controllers[i] = &sim.InputController{}
+		p.Paddle.SetController(s.controllers[i])
+	}
+	s.tick = 0
+	s.status = sim.WorldRunning
+	s.overFor = 0
+}
+
+// Accept clients and run the game until Close
+func (s *Server) Serve() error {
+	go s.run()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Stop accepting and ticking, clients already connected are dropped
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	return s.listener.Close()
+}
+
+func (s *Server) run() {
+	ticker := time.NewTicker(sim.TimePerUpdate)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !s.step() {
+			return
+		}
+	}
+}
+
+// One tick, then the result goes out to every client. Returns false once
+// the server is closed.
+func (s *Server) step() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+
+	switch s.status {
+	case sim.WorldGameOver:
+		s.overFor++
+		if s.overFor >= GameOverTicks {
+			s.newGame()
+		}
+	default:
+		for i, c := range s.controllers {
+			if s.launches[i] {
+				s.world.Launch(i)
+				s.launches[i] = false
+			}
+			//a free paddle stays put
+			if s.players[i] == nil {
+				c.Input = sim.TickInput{}
+			}
+		}
+		s.status = s.world.Update()
+		s.world.TakeEvents()
+		if s.status == sim.WorldLevelComplete {
+			s.world.NextLevel()
+			s.status = sim.WorldRunning
+		}
+		s.tick++
+	}
+
+	state := EncodeState(&State{s.tick, s.status, s.world})
+	for c := range s.clients {
+		select {
+		case <-c.latest:
+		default:
+		}
+		c.latest <- state
+	}
+	return true
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	kind, payload, err := ReadMessage(conn)
+	var join JoinRequest
+	if err == nil && (kind != MsgJoin || Decode(payload, &join) != nil) {
+		err = errors.New("expected a join request")
+	}
+	if err == nil && join.Version != ProtocolVersion {
+		err = fmt.Errorf("server runs protocol version %d, client has %d", ProtocolVersion, join.Version)
+	}
+	if err != nil {
+		WriteMessage(conn, MsgError, err.Error())
+		return
+	}
+
+	c := &serverClient{conn: conn, player: -1, latest: make(chan []byte, 1), done: make(chan struct{})}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if !join.Spectate {
+		for i, p := range s.players {
+			if p == nil {
+				s.players[i] = c
+				c.player = i
+				break
+			}
+		}
+	}
+	s.clients[c] = true
+	mode := s.mode
+	s.mu.Unlock()
+	fmt.Printf("%v joined as %v\n", conn.RemoteAddr(), describePlayer(c.player))
+
+	defer s.leave(c)
+	if err := WriteMessage(conn, MsgWelcome, Welcome{int8(c.player), uint8(mode)}); err != nil {
+		return
+	}
+	go s.writeStates(c)
+	s.readInputs(c)
+}
+
+func describePlayer(player int) string {
+	if player < 0 {
+		return "spectator"
+	}
+	return fmt.Sprintf("player %d", player+1)
+}
+
+func (s *Server) leave(c *serverClient) {
+	close(c.done)
+	s.mu.Lock()
+	delete(s.clients, c)
+	if c.player >= 0 {
+		s.players[c.player] = nil
+	}
+	s.mu.Unlock()
+	fmt.Printf("%v left\n", c.conn.RemoteAddr())
+}
+
+// Until the client hangs up
+func (s *Server) readInputs(c *serverClient) {
+	for {
+		kind, payload, err := ReadMessage(c.conn)
+		if err != nil {
+			return
+		}
+		var in sim.TickInput
+		if kind != MsgInput || c.player < 0 || Decode(payload, &in) != nil {
+			continue
+		}
+		s.mu.Lock()
+		if c.player < len(s.controllers) {
+			s.controllers[c.player].Input = in
+			s.launches[c.player] = s.launches[c.player] || in.Launch
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Each client gets deltas against the last state it was sent, a spectator
+// joining mid-game starts from nothing and so gets the whole world first
+func (s *Server) writeStates(c *serverClient) {
+	var prev []byte
+	for {
+		select {
+		case <-c.done:
+			return
+		case state := <-c.latest:
+			if err := WriteMessage(c.conn, MsgState, Delta(prev, state)); err != nil {
+				c.conn.Close()
+				return
+			}
+			prev = state
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package remote
+
+import (
+	"github.com/CandleEnds/go-breakout/sim"
+	"testing"
+	"time"
+)
+
+// Fail unless ok comes true within a couple of seconds
+func waitFor(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func startServer(t *testing.T, mode sim.GameMode) *Server {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", mode, sim.DefaultStageSize, sim.ClassicLevels{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func dial(t *testing.T, s *Server, spectate bool) *Client {
+	t.Helper()
+	c, err := Dial(s.Addr().String(), spectate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestPlayerAssignment(t *testing.T) {
+	s := startServer(t, sim.ModeCoop)
+	p1 := dial(t, s, false)
+	p2 := dial(t, s, false)
+	extra := dial(t, s, false)
+	watcher := dial(t, s, true)
+	for _, c := range []struct {
+		client *Client
+		want   int
+	}{{p1, 0}, {p2, 1}, {extra, -1}, {watcher, -1}} {
+		if c.client.Player != c.want {
+			t.Errorf("got player %d, want %d", c.client.Player, c.want)
+		}
+		if c.client.Mode != sim.ModeCoop {
+			t.Errorf("got mode %v, want co-op", c.client.Mode)
+		}
+	}
+
+	//a paddle freed by a player leaving goes to the next to join
+	p2.Close()
+	var p3 *Client
+	waitFor(t, "the second paddle to come free", func() bool {
+		c, err := Dial(s.Addr().String(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Player < 0 {
+			c.Close()
+			return false
+		}
+		p3 = c
+		return true
+	})
+	defer p3.Close()
+	if p3.Player != 1 {
+		t.Errorf("rejoining player got paddle %d, want 1", p3.Player)
+	}
+}
+
+func TestInputsMovePaddles(t *testing.T) {
+	s := startServer(t, sim.ModeSingle)
+	player := dial(t, s, false)
+	waitFor(t, "a first state", func() bool { return player.Latest() != nil })
+	start := player.Latest().World.Players[0].Paddle.Pos[0]
+	waitFor(t, "the paddle to move", func() bool {
+		if err := player.SendInput(sim.MakeTickInput(1, false)); err != nil {
+			t.Fatal(err)
+		}
+		return player.Latest().World.Players[0].Paddle.Pos[0] != start
+	})
+}
+
+func TestSpectatorJoinsMidGame(t *testing.T) {
+	s := startServer(t, sim.ModeCoop)
+	player := dial(t, s, false)
+	waitFor(t, "the game to get going", func() bool {
+		player.SendInput(sim.MakeTickInput(0, true))
+		state := player.Latest()
+		return state != nil && state.Tick > 20 && !state.World.Players[0].Serving
+	})
+
+	watcher := dial(t, s, true)
+	waitFor(t, "the spectator's first state", func() bool { return watcher.Latest() != nil })
+	//the first state is sent whole, later ones as deltas on top of it
+	first := watcher.Latest().Tick
+	if first <= 20 {
+		t.Errorf("spectator joined at tick %d, expected mid-game", first)
+	}
+	waitFor(t, "later states", func() bool { return watcher.Latest().Tick > first+10 })
+	if err := watcher.Err(); err != nil {
+		t.Fatal(err)
+	}
+	state := watcher.Latest()
+	if len(state.World.Players) != 2 || len(state.World.Blocks) == 0 {
+		t.Fatalf("spectator sees %d players and %d blocks", len(state.World.Players), len(state.World.Blocks))
+	}
+	if state.World.Players[0].Serving {
+		t.Error("spectator sees the launched ball still being served")
+	}
+	//both clients are watching the same game
+	waitFor(t, "the player to reach the spectator's tick", func() bool {
+		return player.Latest().Tick >= state.Tick
+	})
+}
@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl "github.com/go-gl/mathgl/mgl64"
+)
+
+// A world as the server last saw it
+type State struct {
+	Tick   uint32
+	Status sim.WorldStatus
+	World  *sim.World
+}
+
+type stateHeader struct {
+	Tick      uint32
+	Status    uint8
+	Mode      uint8
+	Level     uint16
+	StageSize mgl.Vec2
+	Players   uint8
+	Blocks    uint16
+}
+
+type playerState struct {
+	Score        int32
+	Lives        int32
+	Serving      bool
+	PaddlePos    mgl.Vec2
+	PaddleSize   mgl.Vec2
+	PaddleSpeed  float64
+	BallPos      mgl.Vec2
+	BallSize     mgl.Vec2
+	BallVelocity mgl.Vec2
+	BallSpeed    float64
+	Owner        int8
+}
+
+type blockState struct {
+	ID    uint16
+	Pos   mgl.Vec2
+	Size  mgl.Vec2
+	Color mgl.Vec4
+	Flash float64
+	HP    int32
+	Solid bool
+}
+
+// Fixed layout, so consecutive states of a game mostly line up byte for
+// byte and Delta can squeeze out what didn't change
+func EncodeState(s *State) []byte {
+	w := s.World
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, stateHeader{
+		s.Tick, uint8(s.Status), uint8(w.Mode), uint16(w.Level), w.StageSize,
+		uint8(len(w.Players)), uint16(len(w.Blocks)),
+	})
+	for _, p := range w.Players {
+		binary.Write(&buf, binary.BigEndian, playerState{
+			int32(p.Score), int32(p.Lives), p.Serving,
+			p.Paddle.Pos, p.Paddle.Size, p.Paddle.Speed,
+			p.Ball.Pos, p.Ball.Size, p.Ball.Velocity, p.Ball.Speed, int8(p.Ball.Owner),
+		})
+	}
+	for _, b := range w.Blocks {
+		binary.Write(&buf, binary.BigEndian, blockState{uint16(b.ID), b.Pos, b.Size, b.Color, b.Flash, int32(b.HP), b.Solid})
+	}
+	return buf.Bytes()
+}
+
+// Rebuild a world from EncodeState's output. Its paddles have no
+// controllers, it's for looking at rather than running.
+func DecodeState(data []byte) (*State, error) {
+	r := bytes.NewReader(data)
+	var header stateHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	players := make([]playerState, header.Players)
+	blocks := make([]blockState, header.Blocks)
+	if err := binary.Read(r, binary.BigEndian, players); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, blocks); err != nil {
+		return nil, err
+	}
+
+	w := &sim.World{
+		StageSize: header.StageSize,
+		Mode:      sim.GameMode(header.Mode),
+		Level:     int(header.Level),
+	}
+	for _, p := range players {
+		paddle := &sim.Paddle{Pos: p.PaddlePos, Size: p.PaddleSize, Speed: p.PaddleSpeed}
+		ball := &sim.Ball{Pos: p.BallPos, Size: p.BallSize, Velocity: p.BallVelocity, Speed: p.BallSpeed, Owner: int(p.Owner)}
+		w.Players = append(w.Players, &sim.Player{
+			Paddle: paddle, Ball: ball, Score: int(p.Score), Lives: int(p.Lives), Serving: p.Serving,
+		})
+	}
+	for _, b := range blocks {
+		w.Blocks = append(w.Blocks, &sim.Block{ID: int(b.ID), Color: b.Color, Flash: b.Flash, Pos: b.Pos, Size: b.Size, Alive: true, HP: int(b.HP), Solid: b.Solid})
+	}
+	return &State{header.Tick, sim.WorldStatus(header.Status), w}, nil
+}
+
+// next encoded against prev: XOR the two, then store runs of unchanged
+// (zero) bytes as counts. prev may be empty, e.g. for a spectator's first
+// state, which then goes out whole.
+//
+// Layout: uvarint length of next, then pairs of uvarint zero run, uvarint
+// literal length and the literal XORed bytes.
+func Delta(prev, next []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(next)))
+	xor := func(i int) byte {
+		if i < len(prev) {
+			return next[i] ^ prev[i]
+		}
+		return next[i]
+	}
+	for i := 0; i < len(next); {
+		zeros := 0
+		for i < len(next) && xor(i) == 0 {
+			zeros++
+			i++
+		}
+		start := i
+		//a couple of zeros inside a literal are cheaper than a new pair
+		for i < len(next) && (xor(i) != 0 || (i+2 < len(next) && (xor(i+1) != 0 || xor(i+2) != 0))) {
+			i++
+		}
+		out = binary.AppendUvarint(out, uint64(zeros))
+		out = binary.AppendUvarint(out, uint64(i-start))
+		for j := start; j < i; j++ {
+			out = append(out, xor(j))
+		}
+	}
+	return out
+}
+
+var errBadDelta = errors.New("corrupt state delta")
+
+// Undo Delta, given the same prev it was made against
+func ApplyDelta(prev, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	length, err := binary.ReadUvarint(r)
+	if err != nil || length > MaxMessageSize {
+		return nil, errBadDelta
+	}
+	next := make([]byte, length)
+	copy(next, prev)
+	for i := uint64(0); i < length; {
+		zeros, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errBadDelta
+		}
+		literal, err := binary.ReadUvarint(r)
+		if err != nil || i+zeros+literal > length {
+			return nil, errBadDelta
+		}
+		i += zeros
+		for end := i + literal; i < end; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, errBadDelta
+			}
+			next[i] ^= b
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,126 @@
+package remote
+
+import (
+	"bytes"
+	"github.com/CandleEnds/go-breakout/sim"
+	"math/rand"
+	"testing"
+)
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+// b with a few bytes changed, like one tick to the next
+func mutate(r *rand.Rand, b []byte) []byte {
+	out := append([]byte(nil), b...)
+	for i := 0; i < len(out)/20+1 && len(out) > 0; i++ {
+		out[r.Intn(len(out))] ^= byte(1 + r.Intn(255))
+	}
+	return out
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	base := randomBytes(r, 300)
+	cases := []struct {
+		name       string
+		prev, next []byte
+	}{
+		{"empty prev", nil, base},
+		{"both empty", nil, nil},
+		{"to empty", base, nil},
+		{"unchanged", base, base},
+		{"few changes", base, mutate(r, base)},
+		{"shrinking", base, mutate(r, base[:120])},
+		{"growing", base[:120], append(mutate(r, base[:120]), randomBytes(r, 200)...)},
+		{"all different", base, randomBytes(r, 300)},
+	}
+	for _, c := range cases {
+		delta := Delta(c.prev, c.next)
+		got, err := ApplyDelta(c.prev, delta)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !bytes.Equal(got, c.next) {
+			t.Errorf("%s: round trip gave %d bytes, want %d", c.name, len(got), len(c.next))
+		}
+	}
+}
+
+func TestDeltaRoundTripRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	prev := []byte(nil)
+	for i := 0; i < 500; i++ {
+		var next []byte
+		switch r.Intn(3) {
+		case 0:
+			next = mutate(r, prev)
+		case 1:
+			next = mutate(r, prev[:r.Intn(len(prev)+1)])
+		default:
+			next = append(mutate(r, prev), randomBytes(r, r.Intn(50))...)
+		}
+		got, err := ApplyDelta(prev, Delta(prev, next))
+		if err != nil || !bytes.Equal(got, next) {
+			t.Fatalf("step %d: round trip of %d bytes against %d failed: %v", i, len(next), len(prev), err)
+		}
+		prev = next
+	}
+}
+
+func TestDeltaUnchangedIsSmall(t *testing.T) {
+	state := EncodeState(&State{1, sim.WorldRunning, sim.MakeWorld(sim.DefaultStageSize, sim.ModeCoop)})
+	if n := len(Delta(state, state)); n > 8 {
+		t.Errorf("delta of an unchanged %d byte state is %d bytes", len(state), n)
+	}
+}
+
+func TestApplyDeltaCorrupt(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	prev := randomBytes(r, 100)
+	delta := Delta(prev, randomBytes(r, 100))
+	if _, err := ApplyDelta(prev, delta[:len(delta)/2]); err == nil {
+		t.Error("truncated delta applied without an error")
+	}
+	if _, err := ApplyDelta(prev, []byte{0xff, 0xff, 0xff, 0xff, 0x7f}); err == nil {
+		t.Error("delta longer than MaxMessageSize applied without an error")
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	w := sim.MakeWorld(sim.DefaultStageSize, sim.ModeVersus)
+	w.SetLevels(sim.GeneratedLevels{Seed: 5})
+	w.Launch(0)
+	for i := 0; i < 30; i++ {
+		w.Update()
+	}
+	data := EncodeState(&State{30, sim.WorldRunning, w})
+	s, err := DecodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Tick != 30 || s.World.Mode != sim.ModeVersus || s.World.Level != w.Level {
+		t.Errorf("decoded tick %d mode %v level %d", s.Tick, s.World.Mode, s.World.Level)
+	}
+	if len(s.World.Players) != 2 || len(s.World.Blocks) != len(w.Blocks) {
+		t.Fatalf("decoded %d players and %d blocks, want 2 and %d", len(s.World.Players), len(s.World.Blocks), len(w.Blocks))
+	}
+	if got, want := s.World.Players[0].Ball.Pos, w.Players[0].Ball.Pos; got != want {
+		t.Errorf("ball at %v, want %v", got, want)
+	}
+	for i, b := range s.World.Blocks {
+		if b.ID != w.Blocks[i].ID || b.Pos != w.Blocks[i].Pos || b.HP != w.Blocks[i].HP {
+			t.Errorf("block %d decoded as %+v", i, b)
+		}
+	}
+	if again := EncodeState(s); !bytes.Equal(again, data) {
+		t.Error("re-encoding the decoded state gave different bytes")
+	}
+	if _, err := DecodeState(data[:len(data)-1]); err == nil {
+		t.Error("truncated state decoded without an error")
+	}
+}
@@ -3,16 +3,14 @@ package main
 import (
 	//"errors"
 	"fmt"
-	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/gl/v3.3-core/gl"
 	//"github.com/go-gl/glu"
 	mgl32 "github.com/go-gl/mathgl/mgl32"
 	mgl "github.com/go-gl/mathgl/mgl64"
 	"image"
 	"image/draw"
 	_ "image/png"
-	"io/ioutil"
 	"math"
-	"os"
 	"strings"
 )
 
@@ -25,20 +23,21 @@ func VertexifyRect(r mgl.Vec2, d float32) (vertices []float32, indices []uint16)
 	lY := float32(0)
 
 	hY := float32(r[1])
-	h := float32(.95)
-	l := float32(.05)
+	h := float32(1)
+	l := float32(0)
 	m := float32(1.0 / 3.0)
 	n := float32(2.0 / 3.0)
 
+	//facing out of the stage
 	vertices = []float32{
-		lX, lY, d, l, l,
-		mX, lY, d, m, l,
-		nX, lY, d, n, l,
-		hX, lY, d, h, l,
-		lX, hY, d, l, h,
-		mX, hY, d, m, h,
-		nX, hY, d, n, h,
-		hX, hY, d, h, h,
+		lX, lY, d, l, l, 0, 0, 1,
+		mX, lY, d, m, l, 0, 0, 1,
+		nX, lY, d, n, l, 0, 0, 1,
+		hX, lY, d, h, l, 0, 0, 1,
+		lX, hY, d, l, h, 0, 0, 1,
+		mX, hY, d, m, h, 0, 0, 1,
+		nX, hY, d, n, h, 0, 0, 1,
+		hX, hY, d, h, h, 0, 0, 1,
 	}
 	indices = []uint16{
 		0, 5, 4,
@@ -51,97 +50,137 @@ func VertexifyRect(r mgl.Vec2, d float32) (vertices []float32, indices []uint16)
 	return
 }
 
-//TODO: Not working yet, maybe something to do with tex coords
+//Cube from the origin to (size, size, size). Each face has its own four
+//vertices so it gets the whole texture and a flat normal.
 func VertexifyCube(size float32) (vertices []float32, indices []uint16) {
-	vertices = []float32{
-		0, 0, 0, 0, 0,
-		0, 0, 1, 0, 1,
-		0, 1, 0, 1, 0,
-		0, 1, 1, 1, 1,
-		1, 0, 0, 0, 0,
-		1, 0, 1, 0, 1,
-		1, 1, 0, 1, 0,
-		1, 1, 1, 1, 1,
+	faces := []struct {
+		normal  mgl.Vec3
+		corners [4]mgl.Vec3 //counter-clockwise seen from outside
+	}{
+		{mgl.Vec3{0, 0, 1}, [4]mgl.Vec3{{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1}}},
+		{mgl.Vec3{0, 0, -1}, [4]mgl.Vec3{{1, 0, 0}, {0, 0, 0}, {0, 1, 0}, {1, 1, 0}}},
+		{mgl.Vec3{1, 0, 0}, [4]mgl.Vec3{{1, 0, 1}, {1, 0, 0}, {1, 1, 0}, {1, 1, 1}}},
+		{mgl.Vec3{-1, 0, 0}, [4]mgl.Vec3{{0, 0, 0}, {0, 0, 1}, {0, 1, 1}, {0, 1, 0}}},
+		{mgl.Vec3{0, 1, 0}, [4]mgl.Vec3{{0, 1, 1}, {1, 1, 1}, {1, 1, 0}, {0, 1, 0}}},
+		{mgl.Vec3{0, -1, 0}, [4]mgl.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 0, 1}, {0, 0, 1}}},
 	}
-	indices = []uint16{
-		0, 4, 2,
-		2, 4, 6,
-		4, 5, 6,
-		6, 5, 7,
-		3, 2, 6,
-		3, 6, 7,
-		1, 0, 2,
-		1, 2, 3,
-		5, 1, 7,
-		1, 3, 7,
-		1, 5, 4,
-		0, 1, 4,
+	uvs := [4]mgl.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+
+	var m Mesh
+	for _, f := range faces {
+		var idx [4]uint16
+		for k, c := range f.corners {
+			idx[k] = m.AddVertex(c.Mul(float64(size)), uvs[k], f.normal)
+		}
+		m.AddQuad(idx[0], idx[1], idx[2], idx[3])
 	}
-	return
+	return m.vertices, m.indices
 }
 
 //make a unit circle centered at the origin with normal Z
 func MakeRenderCircle(resolution uint16) (vertices []float32, indices []uint16) {
-	vertices = make([]float32, resolution)
-	indices = make([]uint16, (resolution-2)*3)
-	increment := 2 * math.Pi / float64(resolution)
-	for angle := float64(0); angle < float64(2)*math.Pi; angle += increment {
-		x := float32(math.Cos(angle))
-		y := float32(math.Sin(angle))
-		vertices = append(vertices, x)
-		vertices = append(vertices, y)
-		vertices = append(vertices, 0)
+	var m Mesh
+	for i := uint16(0); i < resolution; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(resolution)
+		x := math.Cos(angle)
+		y := math.Sin(angle)
 		//tex coords, translate into 0->1 instead of -1->1
-		vertices = append(vertices, (x+1)/2)
-		vertices = append(vertices, (y+1)/2)
+		uv := mgl.Vec2{(x + 1) / 2, (y + 1) / 2}
+		m.AddVertex(mgl.Vec3{x, y, 0}, uv, mgl.Vec3{0, 0, 1})
 	}
-	for idx := uint16(1); idx < resolution-2; idx++ {
-		indices = append(indices, 0)
-		indices = append(indices, idx)
-		indices = append(indices, idx+1)
+	//fan from the first vertex, resolution-2 triangles
+	for idx := uint16(1); idx < resolution-1; idx++ {
+		m.AddTri(0, idx, idx+1)
 	}
-	return
+	return m.vertices, m.indices
 }
 
-func MakeRenderRect(r mgl.Vec2, depth float32, texImg string) *RenderComponent {
+//sprite is a name from the shared atlas, see sprites.json
+func MakeRenderRect(r mgl.Vec2, depth float32, sprite string) (*RenderComponent, error) {
 	vertices, indices := VertexifyRect(r, depth)
-	vao, vbo, indexBuffer := makeVertexArrayObject(vertices, indices)
-	program := GetDefaultShaderProgram()
-	tex, err := createTexture(texImg)
-	if err != nil {
-		panic(err)
-	}
-	comp := MakeRenderComponent(vao, vbo, indexBuffer, int32(len(indices)), tex, program)
-	return &comp
+	return MakeRenderMesh(Mesh{vertices, indices}, sprite)
 }
 
-func MakeRenderCube(size float32, texImg string) *RenderComponent {
+func MakeRenderCube(size float32, sprite string) (*RenderComponent, error) {
 	vertices, indices := VertexifyCube(size)
-	vao, vbo, indexBuffer := makeVertexArrayObject(vertices, indices)
-	program := GetDefaultShaderProgram()
-	tex, err := createTexture(texImg)
+	return MakeRenderMesh(Mesh{vertices, indices}, sprite)
+}
+
+func MakeRenderMesh(m Mesh, sprite string) (*RenderComponent, error) {
+	program, err := GetDefaultShaderProgram()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	comp := MakeRenderComponent(vao, vbo, indexBuffer, int32(len(indices)), tex, program)
-	return &comp
+	vao, vbo, indexBuffer := makeVertexArrayObject(m.vertices, m.indices)
+	if err := checkGLerror("upload mesh for " + sprite); err != nil {
+		return nil, err
+	}
+	comp := MakeRenderComponent(vao, vbo, indexBuffer, int32(len(m.indices)), gAtlas.tex, program)
+	comp.SetTexRegion(gAtlas.Region(sprite))
+	return &comp, nil
 }
 
 var gDefaultProgram uint32 = 0
 
-func GetDefaultShaderProgram() uint32 {
-	if gDefaultProgram == 0 {
-		vSrc := getFileAsString("vert_cylinder_330.glsl")
-		fSrc := getFileAsString("frag_normal_330.glsl")
-		var err error
-		gDefaultProgram, err = makeProgram(vSrc, fSrc)
+func GetDefaultShaderProgram() (uint32, error) {
+	return cachedProgram(&gDefaultProgram, "vert_cylinder", "frag_normal")
+}
+
+var gHUDProgram uint32 = 0
+
+//Flat shader for screen-space overlays, no cylinder warp
+func GetHUDShaderProgram() (uint32, error) {
+	return cachedProgram(&gHUDProgram, "vert_normal", "frag_hud")
+}
+
+var gParticleProgram uint32 = 0
+
+//Cylinder warp with per-vertex colour, positions are absolute stage coords
+func GetParticleShaderProgram() (uint32, error) {
+	return cachedProgram(&gParticleProgram, "vert_particle", "frag_particle")
+}
+
+//Build the program into *cache the first time, failures are retried on the
+//next call
+func cachedProgram(cache *uint32, vert, frag string) (uint32, error) {
+	if *cache == 0 {
+		program, err := loadProgram(vert, frag)
 		if err != nil {
-			panic(err)
+			return 0, err
 		}
+		*cache = program
+	}
+	return *cache, nil
+}
+
+//Shader file for the current GL profile, e.g. "frag_hud" is
+//"frag_hud_330.glsl" on a 3.3+ context and "frag_hud.glsl" on 2.1
+func shaderFile(name string) string {
+	return name + gGLProfile.shaderSuffix + ".glsl"
+}
+
+//vert and frag are shader names, see shaderFile
+func loadProgram(vert, frag string) (uint32, error) {
+	vertFile := shaderFile(vert)
+	fragFile := shaderFile(frag)
+	vSrc, err := getFileAsString(vertFile)
+	if err != nil {
+		return 0, err
+	}
+	fSrc, err := getFileAsString(fragFile)
+	if err != nil {
+		return 0, err
 	}
-	return gDefaultProgram
+	program, err := makeProgram(vSrc, fSrc)
+	if err != nil {
+		return 0, fmt.Errorf("%s + %s: %w", vertFile, fragFile, err)
+	}
+	return program, nil
 }
 
+//Samples the whole texture, see RenderComponent.texRegion
+var FullTexture = mgl.Vec4{0, 0, 1, 1}
+
 type RenderComponent struct {
 	vao         uint32
 	vbo         uint32
@@ -149,11 +188,24 @@ type RenderComponent struct {
 	numIndices  int32
 	program     uint32
 	tex         uint32
+	//multiplied with the texture colour, alpha fades the whole object
+	tint mgl.Vec4
+	//part of the texture to sample: u, v offset then u, v scale
+	texRegion mgl.Vec4
 }
 
 func MakeRenderComponent(vao uint32, vbo uint32, indexBuffer uint32, numIndices int32,
 	tex uint32, program uint32) RenderComponent {
-	return RenderComponent{vao, vbo, indexBuffer, numIndices, program, tex}
+	return RenderComponent{vao, vbo, indexBuffer, numIndices, program, tex,
+		mgl.Vec4{1, 1, 1, 1}, FullTexture}
+}
+
+func (r *RenderComponent) SetTint(tint mgl.Vec4) {
+	r.tint = tint
+}
+
+func (r *RenderComponent) SetTexRegion(region mgl.Vec4) {
+	r.texRegion = region
 }
 
 func glStr(s string) *byte {
@@ -164,10 +216,57 @@ func glAttribLoc(program uint32, name string) uint32 {
 	return uint32(gl.GetAttribLocation(program, glStr(name)))
 }
 
+//Point a float attribute at the bound buffer. Attributes the shader doesn't
+//use (or optimised away) are skipped rather than erroring.
+func bindAttrib(program uint32, name string, size int32, stride int32, offset int) {
+	loc := gl.GetAttribLocation(program, glStr(name))
+	if loc < 0 {
+		return
+	}
+	gl.EnableVertexAttribArray(uint32(loc))
+	gl.VertexAttribPointer(uint32(loc), size, gl.FLOAT, false, stride, gl.PtrOffset(offset))
+}
+
 func glUniformLoc(program uint32, name string) int32 {
 	return gl.GetUniformLocation(program, glStr(name))
 }
 
+//Shape of the cylinder the stage is wrapped around
+const (
+	CylinderRadius = 3
+	CylinderHeight = 0.8
+	LevelHeight    = 3
+)
+
+func setCylinderUniforms(program uint32) {
+	//cylinderRadius uniform
+	crLoc := glUniformLoc(program, "cylinderRadius")
+	gl.Uniform1f(crLoc, CylinderRadius)
+
+	//cylinderHeight uniform
+	chLoc := glUniformLoc(program, "cylinderHeight")
+	gl.Uniform1f(chLoc, CylinderHeight)
+
+	//levelWidth uniform
+	lwLoc := glUniformLoc(program, "levelWidth")
+	gl.Uniform1f(lwLoc, float32(gLevelWidth))
+
+	//levelHeight uniform
+	lhLoc := glUniformLoc(program, "levelHeight")
+	gl.Uniform1f(lhLoc, LevelHeight)
+}
+
+//Same mapping as vert_cylinder, z is height off the stage surface
+func StageToWorld(p mgl.Vec3) mgl.Vec3 {
+	angle := p[0] / gLevelWidth * 2 * math.Pi
+	radius := CylinderRadius + p[2]*2*math.Pi*CylinderRadius/gLevelWidth
+	return mgl.Vec3{
+		radius * math.Sin(angle),
+		p[1] * LevelHeight / CylinderHeight,
+		radius * math.Cos(angle),
+	}
+}
+
 func (r RenderComponent) Draw(pos mgl.Vec2, VP mgl32.Mat4) {
 	// global shader
 	gl.Enable(gl.BLEND)
@@ -175,8 +274,6 @@ func (r RenderComponent) Draw(pos mgl.Vec2, VP mgl32.Mat4) {
 
 	gl.UseProgram(r.program)
 
-	positionAttrib := glAttribLoc(r.program, "position")
-	texCoordAttrib := glAttribLoc(r.program, "texCoord")
 
 	//offset uniform
 	uOffsetLoc := glUniformLoc(r.program, "offset")
@@ -188,21 +285,15 @@ func (r RenderComponent) Draw(pos mgl.Vec2, VP mgl32.Mat4) {
 	vpp := [16]float32(VP)
 	gl.UniformMatrix4fv(uProjLoc, 1, false, &vpp[0])
 
-	//cylinderRadius uniform
-	crLoc := glUniformLoc(r.program, "cylinderRadius")
-	gl.Uniform1f(crLoc, 3)
+	setCylinderUniforms(r.program)
+	setLightingUniforms(r.program)
 
-	//cylinderHeight uniform
-	chLoc := glUniformLoc(r.program, "cylinderHeight")
-	gl.Uniform1f(chLoc, 0.8)
-
-	//levelWidth uniform
-	lwLoc := glUniformLoc(r.program, "levelWidth")
-	gl.Uniform1f(lwLoc, float32(gLevelWidth))
-
-	//levelHeight uniform
-	lhLoc := glUniformLoc(r.program, "levelHeight")
-	gl.Uniform1f(lhLoc, 3)
+	//per-instance tint and texture region uniforms
+	tintLoc := glUniformLoc(r.program, "tint")
+	gl.Uniform4f(tintLoc, float32(r.tint[0]), float32(r.tint[1]), float32(r.tint[2]), float32(r.tint[3]))
+	regionLoc := glUniformLoc(r.program, "texRegion")
+	gl.Uniform4f(regionLoc, float32(r.texRegion[0]), float32(r.texRegion[1]),
+		float32(r.texRegion[2]), float32(r.texRegion[3]))
 
 	//texture sampler uniform
 	samplerLoc := glUniformLoc(r.program, "Sampler")
@@ -212,43 +303,66 @@ func (r RenderComponent) Draw(pos mgl.Vec2, VP mgl32.Mat4) {
 	gl.BindTexture(gl.TEXTURE_2D, r.tex)
 
 	// Setup array buffer stuffer
-	gl.BindVertexArray(r.vao)
+	bindVertexArray(r.vao)
 	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
 	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.indexBuffer)
 
-	//5*4 == 5 floats per vert (x,y,z,u,v) by 4 bytes per float
-	gl.EnableVertexAttribArray(positionAttrib)
-	gl.VertexAttribPointer(positionAttrib, 3, gl.FLOAT, false, 5*4, gl.PtrOffset(0))
-
+	//VertexFloats per vert (x,y,z,u,v,nx,ny,nz) by 4 bytes per float
+	stride := int32(VertexFloats * 4)
+	bindAttrib(r.program, "position", 3, stride, 0)
 	//offset by x,y,z floats
-	gl.EnableVertexAttribArray(texCoordAttrib)
-	gl.VertexAttribPointer(texCoordAttrib, 2, gl.FLOAT, false, 5*4, gl.PtrOffset(3*4))
+	bindAttrib(r.program, "texCoord", 2, stride, 3*4)
+	//offset by x,y,z,u,v floats
+	bindAttrib(r.program, "normal", 3, stride, 5*4)
 
 	// Finally!
 	gl.DrawElements(gl.TRIANGLES, r.numIndices, gl.UNSIGNED_SHORT, nil)
 }
 
-func InitGL() {
+func InitGL() error {
 	// Initialize OpenGL, and print version number to console
-	gl.Init()
+	if err := loadGL(); err != nil {
+		return fmt.Errorf("initialising OpenGL %s: %w", gGLProfile.name, err)
+	}
 	version := gl.GoStr(gl.GetString(gl.VERSION))
-	fmt.Println("OpenGL version", version)
+	fmt.Println("OpenGL version", version, "using", gGLProfile.name, "shaders")
 	gl.Enable(gl.DEPTH_TEST)
 	gl.DepthFunc(gl.LEQUAL)
 	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 	gl.ClearColor(0.9, 0.9, 0.9, 1.0)
+	enableGLDebugOutput()
+	return checkGLerror("init GL")
+}
+
+//Overlays draw on top of everything regardless of depth
+func BeginOverlay() {
+	gl.Disable(gl.DEPTH_TEST)
+}
+
+func EndOverlay() {
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+func SetViewport(width, height int) {
+	gl.Viewport(0, 0, int32(width), int32(height))
+}
+
+//Draw into part of the framebuffer, x and y from its bottom left corner
+func SetViewportRect(x, y, width, height int) {
+	gl.Viewport(int32(x), int32(y), int32(width), int32(height))
 }
 
 func ClearScreen() {
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 }
 
-func getFileAsString(filename string) string {
-	text, err := ioutil.ReadFile(filename)
+//Reads from the asset filesystem, see gAssets
+func getFileAsString(filename string) (string, error) {
+	text, err := readAsset(filename)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
-	return string(text)
+	return string(text), nil
 }
 
 func makeProgram(vertSource, fragSource string) (uint32, error) {
@@ -276,7 +390,8 @@ func makeProgram(vertSource, fragSource string) (uint32, error) {
 		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to link program: %v", log)
+		gl.DeleteProgram(program)
+		return 0, fmt.Errorf("failed to link program: %v", strings.TrimRight(log, "\x00"))
 	}
 
 	return program, nil
@@ -297,15 +412,15 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
-		return 0, fmt.Errorf("failed to compile shader program\n%v\n%v", log, source)
+		gl.DeleteShader(shader)
+		return 0, fmt.Errorf("failed to compile shader program\n%v\n%v", strings.TrimRight(log, "\x00"), source)
 	}
 	return shader, nil
 }
 
 func makeVertexArrayObject(vertices []float32, indices []uint16) (uint32, uint32, uint32) {
-	var vao uint32
-	gl.GenVertexArrays(1, &vao)
-	gl.BindVertexArray(vao)
+	vao := genVertexArray()
+	bindVertexArray(vao)
 
 	var vbo uint32
 	gl.GenBuffers(1, &vbo)
@@ -320,27 +435,73 @@ func makeVertexArrayObject(vertices []float32, indices []uint16) (uint32, uint32
 	return vao, vbo, indexBuffer
 }
 
-func createTexture(file string) (uint32, error) {
-	imgFile, err := os.Open(file)
+//Buffers for geometry that changes every frame, fill with updateVertexArrayObject
+func makeDynamicVertexArrayObject() (uint32, uint32, uint32) {
+	var vbo, indexBuffer uint32
+	vao := genVertexArray()
+	gl.GenBuffers(1, &vbo)
+	gl.GenBuffers(1, &indexBuffer)
+	return vao, vbo, indexBuffer
+}
+
+func updateVertexArrayObject(vao, vbo, indexBuffer uint32, vertices []float32, indices []uint16) {
+	bindVertexArray(vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, indexBuffer)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*2, gl.Ptr(indices), gl.DYNAMIC_DRAW)
+}
+
+func loadImage(file string) (image.Image, error) {
+	imgFile, err := openAsset(file)
 	if err != nil {
-		return 0, fmt.Errorf("Texture %q not found on disk: %v", file, err)
+		return nil, fmt.Errorf("Texture %q not found: %w", file, err)
 	}
+	defer imgFile.Close()
 	img, _, err := image.Decode(imgFile)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("Texture %q: %w", file, err)
+	}
+	return img, nil
+}
+
+//Like loadImage, but a missing or broken file is reported and replaced with
+//a width x height placeholder
+func loadImageOrPlaceholder(file string, width, height int) image.Image {
+	img, err := loadImage(file)
+	if err != nil {
+		fmt.Println(err, "- using placeholder")
+		return makePlaceholderImage(width, height)
 	}
+	return img
+}
+
+func createTexture(file string) (uint32, error) {
+	img := loadImageOrPlaceholder(file, 0, 0)
 	rgba := image.NewRGBA(img.Bounds())
 	if rgba.Stride != rgba.Rect.Size().X*4 {
 		return 0, fmt.Errorf("unsupported stride")
 	}
 	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0,0}, draw.Src)
 
+	return createTextureFromImage(rgba, gl.LINEAR)
+}
+
+//1x1 opaque white, for drawing solid colours through textured shaders
+func createWhiteTexture() (uint32, error) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	copy(rgba.Pix, []uint8{255, 255, 255, 255})
+	return createTextureFromImage(rgba, gl.NEAREST)
+}
+
+//filter is gl.LINEAR or gl.NEAREST, nearest keeps pixel art crisp
+func createTextureFromImage(rgba *image.RGBA, filter int32) (uint32, error) {
 	var textureId uint32
 	gl.GenTextures(1, &textureId)
 	gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindTexture(gl.TEXTURE_2D, textureId)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
 		0,
@@ -351,12 +512,9 @@ func createTexture(file string) (uint32, error) {
 		gl.RGBA,
 		gl.UNSIGNED_BYTE,
 		gl.Ptr(rgba.Pix))
-	return textureId, nil
-}
-
-func checkGLerror() {
-	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
-		// string, _ := glu.ErrorString(glerr)
-		panic("GL Error")
+	if err := checkGLerror("upload texture"); err != nil {
+		gl.DeleteTextures(1, &textureId)
+		return 0, err
 	}
+	return textureId, nil
 }
@@ -0,0 +1,119 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+type Ball struct {
+	Pos      mgl.Vec2
+	Speed    float64
+	Velocity mgl.Vec2
+	Size     mgl.Vec2
+	//player credited with blocks this ball breaks, the last to hit it
+	Owner int
+	//velocity of moving things hit this tick, in stage units per tick
+	carried mgl.Vec2
+}
+
+func MakeBall(radius float64, position mgl.Vec2) *Ball {
+
+	rect := mgl.Vec2{radius * 2, radius * 2}
+	var speed float64 = DefaultBallSpeed * TimePerUpdate.Seconds()
+	velocity := mgl.Vec2{.6, -.8}.Normalize()
+	position[0] -= radius
+	position[1] -= radius
+	return &Ball{position, speed, velocity, rect, 0, mgl.Vec2{}}
+}
+
+//returns true if the ball fell past the paddle, it respawns mid-stage
+func (b *Ball) Update(stageSize mgl.Vec2) bool {
+	b.Pos = b.Pos.Add(b.Velocity.Mul(b.Speed))
+
+	if b.Pos[0] > stageSize[0] {
+		b.Pos[0] -= stageSize[0]
+		//b.Pos[0] = stageSize[0] - b.Size[0]
+		//b.Velocity[0] = -b.Velocity[0]
+	}
+	if b.Pos[0] < 0 {
+		b.Pos[0] += stageSize[0]
+		//b.Pos[0] = 0
+		//b.Velocity[0] = -b.Velocity[0]
+	}
+	if b.Pos[1]+b.Size[1] > stageSize[1] {
+		b.Pos[1] = stageSize[1] - b.Size[1]
+		b.Velocity[1] = -b.Velocity[1]
+	}
+	lost := false
+	if b.Pos[1] < 0 {
+		b.Pos[1] = stageSize[1] / 2
+		lost = true
+	}
+	b.Velocity = b.Velocity.Normalize()
+	return lost
+}
+
+// Sit on top of the paddle, ready to go up and to the right
+func (b *Ball) Serve(p *Paddle) {
+	b.Pos[0] = p.Pos[0] + p.Size[0]/2 - b.Size[0]/2
+	b.Pos[1] = p.Pos[1] + p.Size[1]
+	b.Velocity = mgl.Vec2{.6, .8}.Normalize()
+}
+
+func (b *Ball) GetPos() mgl.Vec2 {
+	return b.Pos.Add(b.Size.Mul(.25))
+}
+
+func (b *Ball) Center() mgl.Vec2 {
+	return b.Pos.Add(b.Size.Mul(.5))
+}
+
+func (b *Ball) GetSize() mgl.Vec2 {
+	return b.Size.Mul(.5)
+}
+
+func (b *Ball) Collided(other Collider, overlap Rect) {
+
+}
+
+//compare two projection vectors for a better fit
+//use larger if same direction, else average (arbitrary decision)
+func BetterProjVal(a, b float64) float64 {
+	if Sign(a) == Sign(b) {
+		return Sign(a) * math.Max(math.Abs(a), math.Abs(b))
+	} else {
+		return (a + b) / 2
+	}
+}
+
+//pvs = projection vectors
+func (b *Ball) ResolveCollision(pvs []mgl.Vec2) {
+	var finalProjVec mgl.Vec2
+	for _, pv := range pvs {
+		finalProjVec[0] = BetterProjVal(finalProjVec[0], pv[0])
+		finalProjVec[1] = BetterProjVal(finalProjVec[1], pv[1])
+	}
+
+	b.Pos = b.Pos.Add(finalProjVec)
+
+	//bounce off as if from a wall moving with whatever was hit, only when
+	//heading into it, and get dragged along the way it moves
+	carried := b.carried.Mul(1 / b.Speed)
+	b.carried = mgl.Vec2{}
+	for i := range b.Velocity {
+		if finalProjVec[i] != 0 && Sign(finalProjVec[i]) != Sign(b.Velocity[i]-carried[i]) {
+			b.Velocity[i] = 2*carried[i] - b.Velocity[i]
+		} else {
+			b.Velocity[i] += carried[i]
+		}
+	}
+	if b.Velocity.Len() > 0 {
+		b.Velocity = b.Velocity.Normalize()
+	}
+}
+
+// v is the velocity of a moving thing the ball hit, in stage units per
+// tick. It's used once the hit is resolved.
+func (b *Ball) Impulse(v mgl.Vec2) {
+	b.carried = b.carried.Add(v)
+}
@@ -0,0 +1,127 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// Seconds a block flashes white after a hit it survives
+const BlockFlashTime = 0.15
+
+type Block struct {
+	//For drawing
+	Color mgl.Vec4
+	//seconds of hit flash left
+	Flash float64
+	//For colliding
+	Pos   mgl.Vec2
+	Size  mgl.Vec2
+	Alive bool
+	//hits left before the block breaks
+	HP int
+	//never breaks, e.g. a maze wall
+	Solid bool
+	//number within its level, it keeps it while it moves
+	ID int
+	//where Motion is centred, Pos for blocks that stay put
+	Home   mgl.Vec2
+	Motion Motion
+	//how far it moved last Update, passed on to balls that hit it
+	Velocity mgl.Vec2
+	//seconds along its Motion
+	age float64
+}
+
+func MakeBlock(size, pos mgl.Vec2, color mgl.Vec3, hp int) *Block {
+	return &Block{color.Vec4(1), 0, pos, size, true, hp, false, 0, pos, Motion{}, mgl.Vec2{}, 0}
+}
+
+func (b *Block) Update(dt float64, stageSize mgl.Vec2) {
+	if b.Flash > 0 {
+		b.Flash -= dt
+	}
+	b.Velocity = mgl.Vec2{}
+	if b.Motion.Kind == MotionNone {
+		return
+	}
+	b.age += dt
+	pos := b.Home.Add(b.Motion.Offset(b.age))
+	pos[0] = math.Mod(pos[0], stageSize[0])
+	if pos[0] < 0 {
+		pos[0] += stageSize[0]
+	}
+	b.Velocity = pos.Sub(b.Pos)
+	//the short way round, not back across the whole stage at the seam
+	if b.Velocity[0] > stageSize[0]/2 {
+		b.Velocity[0] -= stageSize[0]
+	} else if b.Velocity[0] < -stageSize[0]/2 {
+		b.Velocity[0] += stageSize[0]
+	}
+	b.Pos = pos
+}
+
+// Start b along m from where it is now, which becomes its home
+func (b *Block) SetMotion(m Motion, stageSize mgl.Vec2) {
+	b.Home = b.Pos
+	b.Motion = m
+	b.age = 0
+	b.Update(0, stageSize)
+	b.Velocity = mgl.Vec2{}
+}
+
+// Move b, home and all, by d on top of this Update's movement
+func (b *Block) Shift(d mgl.Vec2) {
+	b.Pos = b.Pos.Add(d)
+	b.Home = b.Home.Add(d)
+	b.Velocity = b.Velocity.Add(d)
+}
+
+func (b *Block) Center() mgl.Vec2 {
+	return b.Pos.Add(b.Size.Mul(0.5))
+}
+
+// Block colour blended towards white while flashing
+func (b *Block) Tint() mgl.Vec4 {
+	if b.Flash <= 0 {
+		return b.Color
+	}
+	f := b.Flash / BlockFlashTime
+	white := mgl.Vec4{1, 1, 1, b.Color[3]}
+	return b.Color.Add(white.Sub(b.Color).Mul(f))
+}
+
+func (b *Block) GetPos() mgl.Vec2 {
+	return b.Pos
+}
+
+func (b *Block) GetSize() mgl.Vec2 {
+	return b.Size
+}
+
+func (b *Block) Collided(c Collider, overlap Rect) {
+	//blocks pass through each other, moving ones included
+	if _, ok := c.(*Ball); !ok {
+		return
+	}
+	if b.Velocity != (mgl.Vec2{}) {
+		c.Impulse(b.Velocity)
+	}
+	if b.Solid {
+		b.Flash = BlockFlashTime
+		return
+	}
+	b.HP--
+	if b.HP <= 0 {
+		b.Alive = false
+	} else {
+		b.Flash = BlockFlashTime
+	}
+}
+
+func (b *Block) ResolveCollision(pv []mgl.Vec2) {
+
+}
+
+func (b *Block) Impulse(v mgl.Vec2) {
+
+}
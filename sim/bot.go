@@ -0,0 +1,134 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"math/rand"
+)
+
+// How well a Bot plays
+type BotSkill struct {
+	Name string
+	//ticks between the ball moving and the bot reacting to it
+	Delay int
+	//furthest off the landing point it aims, as a fraction of the paddle
+	//width. Each time the ball comes down it picks a new aim within this.
+	Error float64
+	//ticks it waits with the ball on the paddle before launching
+	ServeDelay int
+}
+
+var BotSkills = []BotSkill{
+	{"EASY", 18, 0.9, 90},
+	{"MEDIUM", 8, 0.5, 60},
+	{"HARD", 0, 0.15, 30},
+}
+
+type botTarget struct {
+	x  float64
+	ok bool
+}
+
+// Plays a paddle by itself, moving to where the ball will come down. It
+// launches its own serves too.
+type Bot struct {
+	world  *World
+	player int
+	Skill  BotSkill
+	rand   *rand.Rand
+	//targets of the last Skill.Delay+1 ticks, oldest first
+	targets []botTarget
+	//aim offset from the landing point, for the ball it was picked for
+	aim     float64
+	aimBall *Ball
+	falling bool
+	//ticks spent serving so far
+	serving int
+}
+
+// Bot for one of w's players. The same seed gives the same game.
+func MakeBot(w *World, player int, skill BotSkill, seed int64) *Bot {
+	return &Bot{world: w, player: player, Skill: skill, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (b *Bot) Update(p *Paddle, stageSize mgl.Vec2) {
+	b.serve()
+	b.targets = append(b.targets, b.target(p))
+	if len(b.targets) > b.Skill.Delay+1 {
+		b.targets = b.targets[1:]
+	}
+	seen := b.targets[0]
+	if !seen.ok {
+		return
+	}
+	d := b.world.WrapDelta(p.Center()[0], seen.x)
+	//ease in on the last step rather than overshooting back and forth
+	p.Steer(math.Max(-1, math.Min(1, d/p.Speed)))
+}
+
+func (b *Bot) serve() {
+	player := b.world.Players[b.player]
+	if !player.Serving || player.Out() {
+		b.serving = 0
+		return
+	}
+	b.serving++
+	if b.serving >= b.Skill.ServeDelay {
+		b.world.Launch(b.player)
+		b.serving = 0
+	}
+}
+
+// Where the paddle's centre should go. Each bot minds its own ball, and
+// only helps with the others while its own is out of play.
+func (b *Bot) target(p *Paddle) botTarget {
+	var ball *Ball
+	var x float64
+	soonest := -1
+	for i, q := range b.world.Players {
+		if q.Out() || q.Serving {
+			continue
+		}
+		landing, ticks, ok := PredictLanding(q.Ball, p, b.world.StageSize)
+		if !ok {
+			continue
+		}
+		if i == b.player {
+			ball, x = q.Ball, landing
+			break
+		}
+		if soonest < 0 || ticks < soonest {
+			ball, x, soonest = q.Ball, landing, ticks
+		}
+	}
+	if ball == nil {
+		return botTarget{}
+	}
+	//a fresh approach, so a fresh mistake
+	falling := ball.Velocity[1] < 0
+	if ball != b.aimBall || (falling && !b.falling) {
+		b.aim = (b.rand.Float64()*2 - 1) * b.Skill.Error * p.Size[0]
+	}
+	b.aimBall, b.falling = ball, falling
+	return botTarget{x + b.aim, true}
+}
+
+// Stage x of the ball's centre when it next comes down to the top of the
+// paddle, and how many ticks away that is. Follows the ball round the
+// cylinder and off the top wall, but not off blocks, which only show up
+// once it's hit them. False if it won't come down in reasonable time.
+func PredictLanding(ball *Ball, p *Paddle, stageSize mgl.Vec2) (float64, int, bool) {
+	b := *ball
+	top := p.Pos[1] + p.Size[1]
+	//long enough to go up and down the whole stage twice
+	limit := int(4 * stageSize[1] / b.Speed)
+	for t := 0; t < limit; t++ {
+		if b.Velocity[1] < 0 && b.GetPos()[1] <= top {
+			return b.Center()[0], t, true
+		}
+		if b.Update(stageSize) {
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import (
 	mgl "github.com/go-gl/mathgl/mgl64"
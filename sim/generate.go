@@ -0,0 +1,280 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Kinds of level GenerateLevel makes
+const (
+	//mirrored left to right
+	PatternSymmetric = iota
+	//smooth blobs from value noise, wrapping round the cylinder
+	PatternNoise
+	//passages of blocks between solid walls
+	PatternMaze
+	NumPatterns
+)
+
+// Columns of generated levels, even so mazes wrap round cleanly
+const GeneratedCols = 10
+
+// How hard a level should be, from its number
+type difficulty struct {
+	rows int
+	//toughest block
+	maxHP int
+	//chance a cell gets a block, for the patterns that care
+	density float64
+	//chance a maze wall stays up
+	walls float64
+}
+
+func levelDifficulty(number int) difficulty {
+	d := float64(number - 1)
+	return difficulty{
+		rows:    MinInt(LevelMaxRows, 4+(number-1)/2),
+		maxHP:   MinInt(5, 1+(number-1)/2),
+		density: math.Min(0.9, 0.55+0.05*d),
+		walls:   math.Min(0.9, 0.35+0.1*d),
+	}
+}
+
+// Level number from seed, always the same for the same two. Each level
+// picks its own pattern; later ones are deeper and tougher.
+func GenerateLevel(seed int64, number int) *Level {
+	r := rand.New(rand.NewSource(seed*7919 + int64(number)))
+	d := levelDifficulty(number)
+	pattern := r.Intn(NumPatterns)
+	var cells [][]byte
+	switch pattern {
+	case PatternSymmetric:
+		cells = symmetricCells(r, d)
+	case PatternNoise:
+		cells = noiseCells(r, d)
+	default:
+		cells = mazeCells(r, d)
+	}
+
+	//every level needs something to break
+	breakable := false
+	for _, row := range cells {
+		for _, c := range row {
+			breakable = breakable || (c >= '1' && c <= '9')
+		}
+	}
+	if !breakable {
+		cells[r.Intn(len(cells))][r.Intn(GeneratedCols)] = '1'
+	}
+
+	l := &Level{
+		Name:   fmt.Sprintf("%s %d-%d", patternNames[pattern], seed, number),
+		Seed:   seed,
+		Number: number,
+	}
+	for _, row := range cells {
+		l.Rows = append(l.Rows, string(row))
+	}
+	//moving walls would break up a maze
+	if pattern != PatternMaze {
+		addMoves(r, l, number)
+	}
+	return l
+}
+
+// Later levels get moving parts: the bottom row orbits from level 3, a
+// ring turns under the blocks from level 5 and the top row orbits the
+// other way from level 7
+func addMoves(r *rand.Rand, l *Level, number int) {
+	if number < 3 {
+		return
+	}
+	rows := len(l.Rows)
+	dir := float64(1 - 2*r.Intn(2))
+	speed := dir * (0.5 + r.Float64())
+	l.Moves = append(l.Moves, RowMotion{rows - 1, Motion{Kind: MotionOrbit, Speed: speed}})
+	if number >= 5 {
+		l.Rings = append(l.Rings, Ring{
+			Col:    float64(r.Intn(GeneratedCols)) + 0.5,
+			Row:    float64(rows) + 1.8,
+			Radius: 1.2,
+			Speed:  dir * 0.25,
+			Blocks: 6,
+			HP:     1,
+		})
+	}
+	if number >= 7 {
+		l.Moves = append(l.Moves, RowMotion{0, Motion{Kind: MotionOrbit, Speed: -speed}})
+	}
+}
+
+// Rows for pressure play from a seed, mirrored like PatternSymmetric and
+// getting tougher every GeneratedRowsPerLevel rows
+type GeneratedRows struct {
+	Seed int64
+}
+
+const GeneratedRowsPerLevel = 8
+
+func (g GeneratedRows) Row(number int) string {
+	r := rand.New(rand.NewSource(g.Seed*7919 - int64(number)))
+	d := levelDifficulty(1 + number/GeneratedRowsPerLevel)
+	row := emptyCells(1, CellEmpty)[0]
+	for c := 0; c < GeneratedCols/2; c++ {
+		if r.Float64() < d.density {
+			cell := blockCell(r, d, 1)
+			row[c] = cell
+			row[GeneratedCols-1-c] = cell
+		}
+	}
+	//an empty row would be a free step
+	if string(row) == strings.Repeat(string(CellEmpty), GeneratedCols) {
+		c := r.Intn(GeneratedCols / 2)
+		row[c], row[GeneratedCols-1-c] = '1', '1'
+	}
+	return string(row)
+}
+
+var patternNames = []string{"MIRROR", "CLOUD", "MAZE"}
+
+func emptyCells(rows int, fill byte) [][]byte {
+	cells := make([][]byte, rows)
+	for i := range cells {
+		cells[i] = make([]byte, GeneratedCols)
+		for j := range cells[i] {
+			cells[i][j] = fill
+		}
+	}
+	return cells
+}
+
+// A random block, tougher towards the top
+func blockCell(r *rand.Rand, d difficulty, row int) byte {
+	hp := 1 + r.Intn(d.maxHP)
+	if row == 0 && hp < d.maxHP {
+		hp++
+	}
+	return byte('0' + hp)
+}
+
+func symmetricCells(r *rand.Rand, d difficulty) [][]byte {
+	cells := emptyCells(d.rows, CellEmpty)
+	for row := range cells {
+		for c := 0; c < GeneratedCols/2; c++ {
+			if r.Float64() < d.density {
+				cell := blockCell(r, d, row)
+				cells[row][c] = cell
+				cells[row][GeneratedCols-1-c] = cell
+			}
+		}
+	}
+	return cells
+}
+
+func noiseCells(r *rand.Rand, d difficulty) [][]byte {
+	//a coarse lattice of random values, smoothly interpolated. Columns
+	//divide the level evenly so the noise meets itself round the back.
+	latticeCols := []int{2, 5}[r.Intn(2)]
+	latticeRows := d.rows/2 + 2
+	lattice := make([][]float64, latticeRows)
+	for i := range lattice {
+		lattice[i] = make([]float64, latticeCols)
+		for j := range lattice[i] {
+			lattice[i][j] = r.Float64()
+		}
+	}
+	smooth := func(t float64) float64 { return t * t * (3 - 2*t) }
+	sample := func(x, y float64) float64 {
+		x0, y0 := int(x), int(y)
+		tx, ty := smooth(x-float64(x0)), smooth(y-float64(y0))
+		x1, y1 := (x0+1)%latticeCols, MinInt(y0+1, latticeRows-1)
+		top := lattice[y0][x0]*(1-tx) + lattice[y0][x1]*tx
+		bottom := lattice[y1][x0]*(1-tx) + lattice[y1][x1]*tx
+		return top*(1-ty) + bottom*ty
+	}
+
+	//denser levels lower the bar for a block
+	threshold := 1 - d.density
+	cells := emptyCells(d.rows, CellEmpty)
+	for row := range cells {
+		for c := range cells[row] {
+			x := float64(c) / GeneratedCols * float64(latticeCols)
+			y := float64(row) / float64(d.rows) * float64(latticeRows-1)
+			v := sample(x, y)
+			if v < threshold {
+				continue
+			}
+			//the peaks are the toughest
+			hp := 1 + int((v-threshold)/(1-threshold)*float64(d.maxHP))
+			cells[row][c] = byte('0' + MinInt(hp, d.maxHP))
+		}
+	}
+	return cells
+}
+
+// Maze cells sit at odd rows and even columns, with walls between them.
+// Passages are carved depth first, then some walls knocked out on easier
+// levels. The bottom wall has a few gaps to let the ball in.
+func mazeCells(r *rand.Rand, d difficulty) [][]byte {
+	rows := d.rows
+	if rows%2 == 0 {
+		rows--
+	}
+	cells := emptyCells(rows, CellSolid)
+	mazeRows, mazeCols := rows/2, GeneratedCols/2
+	visited := make([][]bool, mazeRows)
+	for i := range visited {
+		visited[i] = make([]bool, mazeCols)
+	}
+	open := func(row, col int) {
+		cells[row][(col+GeneratedCols)%GeneratedCols] = blockCell(r, d, row)
+	}
+
+	type cell struct{ row, col int }
+	stack := []cell{{r.Intn(mazeRows), r.Intn(mazeCols)}}
+	visited[stack[0].row][stack[0].col] = true
+	open(stack[0].row*2+1, stack[0].col*2)
+	for len(stack) > 0 {
+		at := stack[len(stack)-1]
+		var next []cell
+		for _, step := range []cell{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			n := cell{at.row + step.row, (at.col + step.col + mazeCols) % mazeCols}
+			if n.row >= 0 && n.row < mazeRows && !visited[n.row][n.col] {
+				next = append(next, n)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		n := next[r.Intn(len(next))]
+		visited[n.row][n.col] = true
+		//the wall between, going the short way round the seam
+		dc := n.col - at.col
+		if dc > 1 {
+			dc = -1
+		} else if dc < -1 {
+			dc = 1
+		}
+		open(at.row+n.row+1, at.col*2+dc)
+		open(n.row*2+1, n.col*2)
+		stack = append(stack, n)
+	}
+
+	//walls between two cells can go, the corners between them stay
+	for row := 1; row < rows-1; row++ {
+		for c := 0; c < GeneratedCols; c++ {
+			between := (row%2 == 1) != (c%2 == 0)
+			if between && cells[row][c] == CellSolid && r.Float64() > d.walls {
+				open(row, c)
+			}
+		}
+	}
+	entrances := MaxInt(1, 3-(d.rows-4)/2)
+	for _, i := range r.Perm(mazeCols)[:entrances] {
+		open(rows-1, i*2)
+	}
+	return cells
+}
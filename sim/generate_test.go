@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateLevelRepeats(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		for number := 1; number <= 10; number++ {
+			a, b := GenerateLevel(seed, number), GenerateLevel(seed, number)
+			if !reflect.DeepEqual(a, b) {
+				t.Fatalf("seed %d level %d came out differently twice:\n%+v\n%+v", seed, number, a, b)
+			}
+		}
+	}
+	if reflect.DeepEqual(GenerateLevel(1, 1).Rows, GenerateLevel(2, 1).Rows) &&
+		reflect.DeepEqual(GenerateLevel(1, 2).Rows, GenerateLevel(2, 2).Rows) {
+		t.Error("seeds 1 and 2 generate the same levels")
+	}
+}
+
+func TestGeneratedLevelsValid(t *testing.T) {
+	moves, rings := 0, 0
+	for seed := int64(-50); seed <= 200; seed++ {
+		for number := 1; number <= 12; number++ {
+			l := GenerateLevel(seed, number)
+			if err := l.Validate(); err != nil {
+				t.Fatalf("seed %d level %d: %v\n%s", seed, number, err, strings.Join(l.Rows, "\n"))
+			}
+			if !strings.ContainsAny(strings.Join(l.Rows, ""), "123456789") {
+				t.Errorf("seed %d level %d has no breakable block in its rows", seed, number)
+			}
+			if l.Seed != seed || l.Number != number {
+				t.Errorf("seed %d level %d saved as seed %d level %d", seed, number, l.Seed, l.Number)
+			}
+			moves += len(l.Moves)
+			rings += len(l.Rings)
+		}
+	}
+	if moves == 0 || rings == 0 {
+		t.Errorf("%d moving rows and %d rings generated, expected some of each", moves, rings)
+	}
+}
+
+func TestLevelSaveLoad(t *testing.T) {
+	custom := &Level{
+		Name:        "CUSTOM",
+		Rows:        []string{"1#.2", "9..3"},
+		BallSpeed:   1.25,
+		PaddleWidth: 0.3,
+		Moves:       []RowMotion{{1, Motion{Kind: MotionBob, Speed: 0.5, Size: 1}}},
+		Rings:       []Ring{{Col: 2, Row: 4, Radius: 1, Speed: -0.25, Blocks: 3, HP: 2}},
+	}
+	for _, l := range []*Level{ClassicLevel(), GenerateLevel(3, 9), custom} {
+		var buf bytes.Buffer
+		if err := l.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadLevel(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", l.Name, err)
+		}
+		if !reflect.DeepEqual(loaded, l) {
+			t.Errorf("%s came back as %+v", l.Name, loaded)
+		}
+	}
+}
+
+func TestLoadLevelValidates(t *testing.T) {
+	for _, bad := range []string{
+		`{"rows": []}`,
+		`{"rows": ["11", "1"]}`,
+		`{"rows": ["1x"]}`,
+		`{"rows": ["##"]}`,
+		`{"rows": ["11"], "moves": [{"row": 1, "kind": "orbit"}]}`,
+		`{"rows": ["11"], "ballSpeed": 100}`,
+	} {
+		if _, err := LoadLevel(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s loaded without an error", bad)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// One player's input for one tick, everything the simulation needs from
+// them. Steering is quantised so every peer applies exactly the same value.
+type TickInput struct {
+	//-127 full speed left to 127 full speed right
+	Steer  int8
+	Launch bool
+}
+
+func MakeTickInput(steer float64, launch bool) TickInput {
+	steer = math.Max(-1, math.Min(1, steer))
+	return TickInput{int8(math.Round(steer * 127)), launch}
+}
+
+func (in TickInput) SteerValue() float64 {
+	return float64(in.Steer) / 127
+}
+
+// Steers a paddle from TickInputs rather than input devices, for players
+// that are somewhere else. Whoever runs the world sets Input before every
+// tick.
+type InputController struct {
+	Input TickInput
+}
+
+func (c *InputController) Update(p *Paddle, stageSize mgl.Vec2) {
+	p.Steer(c.Input.SteerValue())
+}
@@ -0,0 +1,292 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"io"
+)
+
+// A level is a grid of cells spanning the stage, hanging from the top.
+// Each cell is one character: CellEmpty, CellSolid, or '1' to '9' for a
+// block taking that many hits.
+const (
+	CellEmpty = '.'
+	CellSolid = '#'
+)
+
+const (
+	// Height of a row of cells, as a fraction of the stage height
+	LevelRowHeight = 0.075
+	// Deepest a level goes, leaving room to play under it
+	LevelMaxRows = 8
+	LevelMaxCols = 20
+	// Most blocks one ring can have
+	RingMaxBlocks = 24
+	// Toughest block a cell or ring can have
+	MaxBlockHP = 9
+	// Blocks are this much smaller than their cells. Neighbours exactly
+	// touching can overlap by a rounding error and break each other.
+	BlockGap = 1e-6
+
+	// For levels that don't set their own, in stage units per second and
+	// stage units
+	DefaultBallSpeed   = 1.3
+	DefaultPaddleWidth = 0.4
+	// Limits on the level settings, the ball speed as a multiple
+	MinBallSpeed   = 0.5
+	MaxBallSpeed   = 2
+	MinPaddleWidth = 0.1
+	MaxPaddleWidth = 1
+)
+
+// Colour per row, bottom to top
+var RowColors = []mgl.Vec3{
+	{0.2, 0.9, 0.2},
+	{0.95, 0.9, 0.2},
+	{1, 0.55, 0.1},
+	{0.9, 0.2, 0.2},
+}
+
+var SolidColor = mgl.Vec3{0.55, 0.55, 0.6}
+
+// A level as it's saved and shared
+type Level struct {
+	Name string `json:"name,omitempty"`
+	//what GenerateLevel made it from, if it did
+	Seed   int64 `json:"seed,omitempty"`
+	Number int   `json:"number,omitempty"`
+	//top to bottom, all the same length
+	Rows []string `json:"rows"`
+	//times the usual ball speed, 0 for the usual
+	BallSpeed float64 `json:"ballSpeed,omitempty"`
+	//in stage units, 0 for DefaultPaddleWidth
+	PaddleWidth float64 `json:"paddleWidth,omitempty"`
+	//blocks that move
+	Moves []RowMotion `json:"moves,omitempty"`
+	Rings []Ring      `json:"rings,omitempty"`
+}
+
+// Sets every block in a row moving, rows counted from 0 at the top.
+// Distances are in cells, see Motion.
+type RowMotion struct {
+	Row int `json:"row"`
+	Motion
+}
+
+// Blocks spaced evenly round a turning circle, over and above the grid
+type Ring struct {
+	//centre, in cells from the top left of the level
+	Col float64 `json:"col"`
+	Row float64 `json:"row"`
+	//in rows
+	Radius float64 `json:"radius"`
+	//turns per second, negative going clockwise
+	Speed  float64 `json:"speed"`
+	Blocks int     `json:"blocks"`
+	HP     int     `json:"hp"`
+}
+
+// The rectangle of blocks the game always had. Every block took one hit
+// at first; the top row has taken two since blocks were tinted by the
+// hits they have left.
+func ClassicLevel() *Level {
+	return &Level{
+		Name: "CLASSIC",
+		Rows: []string{
+			"2222222222",
+			"1111111111",
+			"1111111111",
+			"1111111111",
+		},
+	}
+}
+
+func LoadLevel(r io.Reader) (*Level, error) {
+	var l Level
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return nil, err
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (l *Level) Save(w io.Writer) error {
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (l *Level) Validate() error {
+	if len(l.Rows) == 0 || len(l.Rows) > LevelMaxRows {
+		return fmt.Errorf("level has %d rows, expected 1 to %d", len(l.Rows), LevelMaxRows)
+	}
+	cols := len(l.Rows[0])
+	if cols == 0 || cols > LevelMaxCols {
+		return fmt.Errorf("level has %d columns, expected 1 to %d", cols, LevelMaxCols)
+	}
+	breakable := 0
+	for r, row := range l.Rows {
+		if len(row) != cols {
+			return fmt.Errorf("level row %d has %d cells, expected %d", r+1, len(row), cols)
+		}
+		for _, c := range row {
+			switch {
+			case c >= '1' && c <= '9':
+				breakable++
+			case c != CellEmpty && c != CellSolid:
+				return fmt.Errorf("level row %d has unknown cell %q", r+1, c)
+			}
+		}
+	}
+	for _, m := range l.Moves {
+		if m.Row < 0 || m.Row >= len(l.Rows) {
+			return fmt.Errorf("level moves row %d, expected 0 to %d", m.Row, len(l.Rows)-1)
+		}
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("level row %d: %w", m.Row, err)
+		}
+	}
+	for i, r := range l.Rings {
+		if r.Blocks < 1 || r.Blocks > RingMaxBlocks {
+			return fmt.Errorf("level ring %d has %d blocks, expected 1 to %d", i+1, r.Blocks, RingMaxBlocks)
+		}
+		if r.HP < 1 || r.HP > MaxBlockHP {
+			return fmt.Errorf("level ring %d has %d HP, expected 1 to %d", i+1, r.HP, MaxBlockHP)
+		}
+		if r.Radius <= 0 {
+			return fmt.Errorf("level ring %d has radius %v", i+1, r.Radius)
+		}
+		breakable += r.Blocks
+	}
+	if breakable == 0 {
+		return errors.New("level has no blocks to break")
+	}
+	if l.BallSpeed != 0 && (l.BallSpeed < MinBallSpeed || l.BallSpeed > MaxBallSpeed) {
+		return fmt.Errorf("level ball speed %v, expected %v to %v", l.BallSpeed, MinBallSpeed, MaxBallSpeed)
+	}
+	if l.PaddleWidth != 0 && (l.PaddleWidth < MinPaddleWidth || l.PaddleWidth > MaxPaddleWidth) {
+		return fmt.Errorf("level paddle width %v, expected %v to %v", l.PaddleWidth, MinPaddleWidth, MaxPaddleWidth)
+	}
+	return nil
+}
+
+func (l *Level) Cols() int {
+	return len(l.Rows[0])
+}
+
+// Times the usual ball speed
+func (l *Level) BallScale() float64 {
+	if l.BallSpeed != 0 {
+		return l.BallSpeed
+	}
+	return 1
+}
+
+// Distance the ball covers each tick
+func (l *Level) BallStep() float64 {
+	return DefaultBallSpeed * l.BallScale() * TimePerUpdate.Seconds()
+}
+
+// Width of the paddles on this level
+func (l *Level) PaddleSpan() float64 {
+	if l.PaddleWidth != 0 {
+		return l.PaddleWidth
+	}
+	return DefaultPaddleWidth
+}
+
+// Size of one cell on a stage
+func (l *Level) CellSize(stageSize mgl.Vec2) mgl.Vec2 {
+	return mgl.Vec2{stageSize[0] / float64(l.Cols()), stageSize[1] * LevelRowHeight}
+}
+
+// The level's blocks, laid out on a stage and numbered in order
+func (l *Level) Blocks(stageSize mgl.Vec2) []*Block {
+	cell := l.CellSize(stageSize)
+	size := cell.Sub(mgl.Vec2{BlockGap, BlockGap})
+	moves := make(map[int]Motion)
+	for _, m := range l.Moves {
+		moves[m.Row] = m.Motion.scaled(cell)
+	}
+	var blocks []*Block
+	for r, row := range l.Rows {
+		posy := stageSize[1] - float64(r+1)*cell[1]
+		color := RowColors[(len(l.Rows)-1-r)%len(RowColors)]
+		for _, b := range rowBlocks(row, posy, cell, color) {
+			if m, ok := moves[r]; ok {
+				b.SetMotion(m, stageSize)
+			}
+			blocks = append(blocks, b)
+		}
+	}
+	for _, ring := range l.Rings {
+		center := mgl.Vec2{ring.Col * cell[0], stageSize[1] - ring.Row*cell[1]}
+		color := RowColors[(ring.HP-1)%len(RowColors)]
+		for i := 0; i < ring.Blocks; i++ {
+			b := MakeBlock(size, center.Sub(size.Mul(0.5)), color, ring.HP)
+			m := Motion{MotionRing, ring.Speed, ring.Radius, float64(i) / float64(ring.Blocks)}
+			b.SetMotion(m.scaled(cell), stageSize)
+			blocks = append(blocks, b)
+		}
+	}
+	for i, b := range blocks {
+		b.ID = i
+	}
+	return blocks
+}
+
+// Blocks for one row of cells, the bottom of the row at y
+func rowBlocks(row string, y float64, cell mgl.Vec2, color mgl.Vec3) []*Block {
+	size := cell.Sub(mgl.Vec2{BlockGap, BlockGap})
+	var blocks []*Block
+	for c, cellType := range row {
+		pos := mgl.Vec2{float64(c) * cell[0], y}
+		switch {
+		case cellType == CellSolid:
+			b := MakeBlock(size, pos, SolidColor, 1)
+			b.Solid = true
+			blocks = append(blocks, b)
+		case cellType >= '1' && cellType <= '9':
+			blocks = append(blocks, MakeBlock(size, pos, color, int(cellType-'0')))
+		}
+	}
+	return blocks
+}
+
+// Where a World's levels come from, numbered from 1
+type LevelSource interface {
+	Level(number int) *Level
+}
+
+// ClassicLevel every time
+type ClassicLevels struct{}
+
+func (ClassicLevels) Level(number int) *Level {
+	return ClassicLevel()
+}
+
+// A new level each time from GenerateLevel, the same ones for the same seed
+type GeneratedLevels struct {
+	Seed int64
+}
+
+func (g GeneratedLevels) Level(number int) *Level {
+	return GenerateLevel(g.Seed, number)
+}
+
+// Levels in order, then round again. An empty list has one empty level.
+type LevelList []*Level
+
+func (l LevelList) Level(number int) *Level {
+	if len(l) == 0 {
+		return &Level{}
+	}
+	return l[(number-1)%len(l)]
+}
@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"fmt"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// Paths a block can follow
+type MotionKind string
+
+const (
+	MotionNone MotionKind = ""
+	//round the cylinder at a steady speed
+	MotionOrbit MotionKind = "orbit"
+	//up and down about home
+	MotionBob MotionKind = "bob"
+	//round a circle about home
+	MotionRing MotionKind = "ring"
+)
+
+// A path about a block's home, worked out afresh from the time so blocks
+// never drift off it. In a Level distances are in cells: orbit speed in
+// columns per second, bob and ring size in rows. On a Block they are in
+// stage units.
+type Motion struct {
+	Kind MotionKind `json:"kind"`
+	//orbit speed, negative going left, or bob and ring turns per second
+	Speed float64 `json:"speed"`
+	//bob height either side of home, or ring radius
+	Size float64 `json:"size,omitempty"`
+	//bob and ring turns to start from
+	Phase float64 `json:"phase,omitempty"`
+}
+
+func (m Motion) Validate() error {
+	switch m.Kind {
+	case MotionOrbit, MotionBob, MotionRing:
+		return nil
+	}
+	return fmt.Errorf("unknown motion %q", m.Kind)
+}
+
+// Offset from home after t seconds
+func (m Motion) Offset(t float64) mgl.Vec2 {
+	angle := 2 * math.Pi * (m.Speed*t + m.Phase)
+	switch m.Kind {
+	case MotionOrbit:
+		return mgl.Vec2{m.Speed * t, 0}
+	case MotionBob:
+		return mgl.Vec2{0, m.Size * math.Sin(angle)}
+	case MotionRing:
+		return mgl.Vec2{m.Size * math.Cos(angle), m.Size * math.Sin(angle)}
+	}
+	return mgl.Vec2{}
+}
+
+// Distances in cells to stage units
+func (m Motion) scaled(cell mgl.Vec2) Motion {
+	switch m.Kind {
+	case MotionOrbit:
+		m.Speed *= cell[0]
+	default:
+		m.Size *= cell[1]
+	}
+	return m
+}
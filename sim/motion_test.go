@@ -0,0 +1,152 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"testing"
+)
+
+func near(a, b mgl.Vec2) bool {
+	return math.Abs(a[0]-b[0]) < 1e-9 && math.Abs(a[1]-b[1]) < 1e-9
+}
+
+func TestMotionOffset(t *testing.T) {
+	cases := []struct {
+		name   string
+		motion Motion
+		t      float64
+		want   mgl.Vec2
+	}{
+		{"still", Motion{}, 3, mgl.Vec2{}},
+		{"orbit right", Motion{Kind: MotionOrbit, Speed: 2}, 1.5, mgl.Vec2{3, 0}},
+		{"orbit left", Motion{Kind: MotionOrbit, Speed: -0.5}, 2, mgl.Vec2{-1, 0}},
+		//the phase only turns bobs and rings
+		{"orbit phase", Motion{Kind: MotionOrbit, Speed: 1, Phase: 0.5}, 0, mgl.Vec2{}},
+		{"bob start", Motion{Kind: MotionBob, Speed: 0.25, Size: 2}, 0, mgl.Vec2{}},
+		{"bob top", Motion{Kind: MotionBob, Speed: 0.25, Size: 2}, 1, mgl.Vec2{0, 2}},
+		{"bob half", Motion{Kind: MotionBob, Speed: 0.25, Size: 2}, 2, mgl.Vec2{}},
+		{"bob bottom", Motion{Kind: MotionBob, Speed: 0.25, Size: 2}, 3, mgl.Vec2{0, -2}},
+		{"bob period", Motion{Kind: MotionBob, Speed: 0.25, Size: 2}, 4, mgl.Vec2{}},
+		{"bob phase", Motion{Kind: MotionBob, Speed: 0.25, Size: 2, Phase: 0.25}, 0, mgl.Vec2{0, 2}},
+		{"ring start", Motion{Kind: MotionRing, Speed: 1, Size: 2}, 0, mgl.Vec2{2, 0}},
+		{"ring quarter", Motion{Kind: MotionRing, Speed: 1, Size: 2}, 0.25, mgl.Vec2{0, 2}},
+		{"ring clockwise", Motion{Kind: MotionRing, Speed: -1, Size: 2}, 0.25, mgl.Vec2{0, -2}},
+		{"ring phase", Motion{Kind: MotionRing, Speed: 1, Size: 2, Phase: 0.5}, 0, mgl.Vec2{-2, 0}},
+		{"ring period", Motion{Kind: MotionRing, Speed: 0.5, Size: 2}, 2, mgl.Vec2{2, 0}},
+	}
+	for _, c := range cases {
+		if got := c.motion.Offset(c.t); !near(got, c.want) {
+			t.Errorf("%s: offset %v at %vs, want %v", c.name, got, c.t, c.want)
+		}
+	}
+}
+
+func TestOrbitWrapsAtSeam(t *testing.T) {
+	stage := DefaultStageSize
+	dt := TimePerUpdate.Seconds()
+	for _, speed := range []float64{1, -1} {
+		b := MakeBlock(mgl.Vec2{0.1, 0.1}, mgl.Vec2{stage[0] - 0.05, 1}, mgl.Vec3{}, 1)
+		if speed < 0 {
+			b = MakeBlock(mgl.Vec2{0.1, 0.1}, mgl.Vec2{0.05, 1}, mgl.Vec3{}, 1)
+		}
+		b.SetMotion(Motion{Kind: MotionOrbit, Speed: speed}, stage)
+		for i := 0; i < 60; i++ {
+			b.Update(dt, stage)
+			if b.Pos[0] < 0 || b.Pos[0] >= stage[0] {
+				t.Fatalf("speed %v tick %d: block at x %v, off the stage", speed, i, b.Pos[0])
+			}
+			//a step across the seam is still a small one
+			if !near(b.Velocity, mgl.Vec2{speed * dt, 0}) {
+				t.Fatalf("speed %v tick %d: velocity %v, want %v", speed, i, b.Velocity, speed*dt)
+			}
+		}
+		//60 ticks' travel, and back onto the stage
+		want := math.Mod(b.Home[0]+speed*60*dt+stage[0], stage[0])
+		if math.Abs(b.Pos[0]-want) > 1e-9 {
+			t.Errorf("speed %v: ended at x %v, want %v", speed, b.Pos[0], want)
+		}
+	}
+}
+
+func TestBobReturnsHome(t *testing.T) {
+	stage := DefaultStageSize
+	b := MakeBlock(mgl.Vec2{0.1, 0.1}, mgl.Vec2{0.5, 1}, mgl.Vec3{}, 1)
+	//a period of 120 ticks
+	b.SetMotion(Motion{Kind: MotionBob, Speed: 1 / (120 * TimePerUpdate.Seconds()), Size: 0.2}, stage)
+	home := b.Pos
+	highest := home[1]
+	for i := 0; i < 120; i++ {
+		b.Update(TimePerUpdate.Seconds(), stage)
+		highest = math.Max(highest, b.Pos[1])
+	}
+	if !near(b.Pos, home) {
+		t.Errorf("after a period at %v, want home %v", b.Pos, home)
+	}
+	if math.Abs(highest-home[1]-0.2) > 1e-3 {
+		t.Errorf("rose %v, want 0.2", highest-home[1])
+	}
+}
+
+func TestRingLayout(t *testing.T) {
+	l := &Level{
+		Rows:  []string{"1111"},
+		Rings: []Ring{{Col: 2, Row: 3, Radius: 1, Speed: 0.5, Blocks: 4, HP: 2}},
+	}
+	stage := DefaultStageSize
+	cell := l.CellSize(stage)
+	center := mgl.Vec2{2 * cell[0], stage[1] - 3*cell[1]}
+	blocks := l.Blocks(stage)
+	if len(blocks) != 8 {
+		t.Fatalf("%d blocks, want 4 in the row and 4 in the ring", len(blocks))
+	}
+	//evenly round the circle, starting to the right, radius in rows
+	want := []mgl.Vec2{{cell[1], 0}, {0, cell[1]}, {-cell[1], 0}, {0, -cell[1]}}
+	for i, b := range blocks[4:] {
+		if got := b.Center().Sub(center); !near(got, want[i]) {
+			t.Errorf("ring block %d at %v from the centre, want %v", i, got, want[i])
+		}
+		if b.HP != 2 || b.ID != 4+i {
+			t.Errorf("ring block %d has %d HP and ID %d", i, b.HP, b.ID)
+		}
+	}
+	//half a turn a second
+	for i := 0; i < 15; i++ {
+		for _, b := range blocks {
+			b.Update(TimePerUpdate.Seconds(), stage)
+		}
+	}
+	turned := 2 * math.Pi * 0.5 * 15 * TimePerUpdate.Seconds()
+	for i, b := range blocks[4:] {
+		angle := turned + float64(i)*math.Pi/2
+		want := mgl.Vec2{cell[1] * math.Cos(angle), cell[1] * math.Sin(angle)}
+		if got := b.Center().Sub(center); !near(got, want) {
+			t.Errorf("ring block %d at %v after 15 ticks, want %v", i, got, want)
+		}
+	}
+}
+
+// A ball going straight up into a block bounces straight down off a still
+// one, and is dragged the way a moving one goes
+func TestMovingBlockCarriesBall(t *testing.T) {
+	stage := DefaultStageSize
+	for _, speed := range []float64{0, 2, -2, 0.5} {
+		block := MakeBlock(mgl.Vec2{0.15, 0.1}, mgl.Vec2{0.6, 1}, mgl.Vec3{}, 2)
+		if speed != 0 {
+			block.SetMotion(Motion{Kind: MotionOrbit, Speed: speed}, stage)
+		}
+		block.Update(TimePerUpdate.Seconds(), stage)
+		ball := MakeBall(0.05, mgl.Vec2{block.Center()[0], block.Pos[1] - 0.01})
+		ball.Velocity = mgl.Vec2{0, 1}
+		CollideAll([]Collider{ball, block})
+
+		if block.HP != 1 {
+			t.Errorf("speed %v: block not hit", speed)
+		}
+		//the block's velocity in ball speeds, then bounced off it
+		carried := speed / DefaultBallSpeed
+		want := mgl.Vec2{carried, -1}.Normalize()
+		if !near(ball.Velocity, want) {
+			t.Errorf("speed %v: ball velocity %v, want %v", speed, ball.Velocity, want)
+		}
+	}
+}
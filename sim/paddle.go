@@ -0,0 +1,112 @@
+package sim
+
+import (
+	//"fmt"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+// Steers a paddle, once per tick before it moves
+type Controller interface {
+	Update(p *Paddle, stageSize mgl.Vec2)
+}
+
+type Paddle struct {
+	controller Controller
+	Pos        mgl.Vec2
+	Speed      float64
+	//proportional speed this tick, negative is left, cleared by Update
+	steer float64
+	Size  mgl.Vec2
+}
+
+// center is the stage x the paddle starts around
+func MakePaddle(width, center float64, sceneSize mgl.Vec2, controller Controller) *Paddle {
+	size := mgl.Vec2{width, 0.15}
+	pos := mgl.Vec2{center - width/2, 0.05 * sceneSize[1]}
+	speed := 1 * TimePerUpdate.Seconds()
+	return &Paddle{controller, pos, float64(speed), 0, size}
+}
+
+func (p *Paddle) GetController() Controller {
+	return p.controller
+}
+
+func (p *Paddle) SetController(c Controller) {
+	p.controller = c
+}
+
+func (p *Paddle) Center() mgl.Vec2 {
+	return p.Pos.Add(p.Size.Mul(0.5))
+}
+
+func (p *Paddle) GetPos() mgl.Vec2 {
+	return p.Pos
+}
+
+func (p *Paddle) GetSize() mgl.Vec2 {
+	return p.Size
+}
+
+// Without a controller the paddle stays where it is
+func (p *Paddle) Update(stageSize mgl.Vec2) {
+	if p.controller != nil {
+		p.controller.Update(p, stageSize)
+	}
+	//all inputs together never go faster than full speed
+	v := math.Max(-1, math.Min(1, p.steer))
+	p.steer = 0
+	p.Nudge(p.Speed*v, stageSize)
+
+	//fmt.Println(p.Pos[0])
+}
+
+//Move sideways by dx, wrapping round the stage
+func (p *Paddle) Nudge(dx float64, stageSize mgl.Vec2) {
+	p.Pos[0] += dx
+	if p.Pos[0] > stageSize[0] {
+		p.Pos[0] -= stageSize[0]
+		//p.Pos[0] = stageSize[0] - p.Size[0]
+	} else if p.Pos[0] < 0 {
+		p.Pos[0] += stageSize[0]
+		//p.Pos[0] = 0
+	}
+}
+
+//Add proportional speed for the next Update, e.g. from an analog stick
+func (p *Paddle) Steer(x float64) {
+	p.steer += x
+}
+
+//Speed asked for so far this tick
+func (p *Paddle) Steering() float64 {
+	return p.steer
+}
+
+func (p *Paddle) Stop() {
+	p.steer = 0
+}
+
+func (p *Paddle) Collided(c Collider, overlap Rect) {
+	/*
+		impulse := mgl.Vec2{0, 0}
+		if overlap.Height() > overlap.Width() {
+			impulse[0] = 1
+		} else {
+			center := overlap.Center()[0]
+			padcenter := p.Pos[0] + p.Size[0]/2
+			norm := (center - padcenter) / p.Size[0] * 2
+			impulse[0] = norm
+		}
+		fmt.Println(impulse[0])
+		c.Impulse(impulse)
+	*/
+}
+
+func (p *Paddle) ResolveCollision(pv []mgl.Vec2) {
+
+}
+
+func (p *Paddle) Impulse(v mgl.Vec2) {
+
+}
@@ -0,0 +1,181 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"io"
+	"math"
+)
+
+// Settings for pressure play, where the whole block field steps down a row
+// at a time while new rows come in at the top. A block reaching the
+// paddles ends the game.
+type Pressure struct {
+	//seconds between steps at the start
+	StepTime float64 `json:"stepTime"`
+	//time between steps is multiplied by this after each one, down to
+	//MinStepTime
+	Speedup     float64 `json:"speedup"`
+	MinStepTime float64 `json:"minStepTime"`
+	//seconds a step takes to slide down a row, 0 to jump straight there
+	SlideTime float64 `json:"slideTime"`
+	//rows already in place at the start
+	StartRows int `json:"startRows"`
+	//where new rows come from, not saved
+	Rows RowSource `json:"-"`
+}
+
+// Pressure settings before any file changes them. Rows has to be set.
+var DefaultPressure = Pressure{
+	StepTime:    8,
+	Speedup:     0.97,
+	MinStepTime: 2,
+	SlideTime:   0.4,
+	StartRows:   4,
+}
+
+// Settings from r over DefaultPressure, so a file only needs the ones it
+// changes
+func LoadPressure(r io.Reader) (*Pressure, error) {
+	p := DefaultPressure
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Pressure) Validate() error {
+	if p.MinStepTime <= 0 || p.StepTime < p.MinStepTime {
+		return fmt.Errorf("pressure step time %v, min step time %v, expected 0 < min <= step", p.StepTime, p.MinStepTime)
+	}
+	if p.Speedup <= 0 || p.Speedup > 1 {
+		return fmt.Errorf("pressure speedup %v, expected more than 0 up to 1", p.Speedup)
+	}
+	if p.SlideTime < 0 || p.SlideTime > p.MinStepTime {
+		return fmt.Errorf("pressure slide time %v, expected 0 to %v", p.SlideTime, p.MinStepTime)
+	}
+	if p.StartRows < 0 || p.StartRows > LevelMaxRows {
+		return fmt.Errorf("pressure start rows %d, expected 0 to %d", p.StartRows, LevelMaxRows)
+	}
+	return nil
+}
+
+// Where a pressure game's rows come from, numbered from 0 in the order
+// they come in. Rows are in the same cells as a Level's.
+type RowSource interface {
+	Row(number int) string
+}
+
+// The rows of a LevelSource's levels, each level read bottom to top so it
+// ends up the right way up, then on to the next level. A source that runs
+// out of rows carries on with GeneratedRows.
+type LevelRows struct {
+	Levels LevelSource
+}
+
+// Levels in a row without any rows before LevelRows gives up on its source
+const maxEmptyLevels = 100
+
+func (s LevelRows) Row(number int) string {
+	for level, empty := 1, 0; empty < maxEmptyLevels; level++ {
+		rows := s.Levels.Level(level).Rows
+		if len(rows) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		if number < len(rows) {
+			return rows[len(rows)-1-number]
+		}
+		number -= len(rows)
+	}
+	return GeneratedRows{}.Row(number)
+}
+
+// How far a pressure game has got
+type creepState struct {
+	//seconds until the next step, and between steps by now
+	wait, interval float64
+	//distance still to fall in the step under way
+	fall float64
+	//rows brought in so far, the next one's number
+	rows int
+	//for the next block brought in
+	nextID int
+}
+
+// Play pressure from now on, starting the field over. Call it after
+// SetLevels, which puts the level's blocks back.
+func (w *World) SetPressure(p *Pressure) {
+	w.Pressure = p
+	w.creep = creepState{wait: p.StepTime, interval: p.StepTime}
+	w.Blocks = nil
+	//the first row in is the lowest
+	for i := 0; i < p.StartRows; i++ {
+		w.addRow(w.StageSize[1] - float64(p.StartRows-i)*w.rowHeight())
+	}
+}
+
+// Rows brought in so far in a pressure game
+func (w *World) PressureRows() int {
+	return w.creep.rows
+}
+
+func (w *World) rowHeight() float64 {
+	return w.StageSize[1] * LevelRowHeight
+}
+
+// The next row from the RowSource, its bottom edge at y
+func (w *World) addRow(y float64) {
+	row := w.Pressure.Rows.Row(w.creep.rows)
+	cell := mgl.Vec2{w.StageSize[0] / float64(len(row)), w.rowHeight()}
+	color := RowColors[w.creep.rows%len(RowColors)]
+	for _, b := range rowBlocks(row, y, cell, color) {
+		b.ID = w.creep.nextID
+		w.creep.nextID++
+		w.Blocks = append(w.Blocks, b)
+	}
+	w.creep.rows++
+}
+
+// Count down to the next step, and slide the field down during one
+func (w *World) creepDown(dt float64) {
+	p, c := w.Pressure, &w.creep
+	c.wait -= dt
+	if c.wait <= 0 {
+		//comes in above the top, to slide down with the rest
+		w.addRow(w.StageSize[1])
+		c.fall += w.rowHeight()
+		c.interval = math.Max(p.MinStepTime, c.interval*p.Speedup)
+		c.wait = c.interval
+	}
+	if c.fall <= 0 {
+		return
+	}
+	d := c.fall
+	if p.SlideTime > 0 {
+		d = math.Min(d, w.rowHeight()*dt/p.SlideTime)
+	}
+	c.fall -= d
+	for _, b := range w.Blocks {
+		b.Shift(mgl.Vec2{0, -d})
+	}
+}
+
+// A block has come down as far as the top of the paddles
+func (w *World) overrun() bool {
+	top := 0.0
+	for _, p := range w.Players {
+		top = math.Max(top, p.Paddle.Pos[1]+p.Paddle.Size[1])
+	}
+	for _, b := range w.Blocks {
+		if b.Pos[1] <= top {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package sim
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func pressureWorld(p Pressure) *World {
+	w := MakeWorld(DefaultStageSize, ModeSingle)
+	if p.Rows == nil {
+		p.Rows = GeneratedRows{Seed: 1}
+	}
+	w.SetPressure(&p)
+	return w
+}
+
+// Ticks until Update has brought in another row
+func ticksToStep(t *testing.T, w *World) int {
+	t.Helper()
+	rows := w.PressureRows()
+	for ticks := 1; ticks < 100000; ticks++ {
+		if w.Update() == WorldGameOver {
+			t.Fatalf("game over waiting for row %d", rows)
+		}
+		if w.PressureRows() > rows {
+			return ticks
+		}
+	}
+	t.Fatal("no row came in")
+	return 0
+}
+
+func secondsToTicks(s float64) int {
+	return int(math.Round(s / TimePerUpdate.Seconds()))
+}
+
+func TestPressureSpeedsUp(t *testing.T) {
+	w := pressureWorld(Pressure{StepTime: 2, Speedup: 0.5, MinStepTime: 0.4, StartRows: 1})
+	//down to the minimum, then staying there
+	for i, want := range []float64{2, 1, 0.5, 0.4, 0.4} {
+		got := ticksToStep(t, w)
+		if d := got - secondsToTicks(want); d < -1 || d > 1 {
+			t.Errorf("step %d after %d ticks, want %v seconds", i, got, want)
+		}
+	}
+}
+
+func TestPressureSlidesARow(t *testing.T) {
+	p := Pressure{StepTime: 1, Speedup: 1, MinStepTime: 1, SlideTime: 0.5, StartRows: 2}
+	w := pressureWorld(p)
+	lowest := w.Blocks[0]
+	start := lowest.Pos[1]
+	ticksToStep(t, w)
+	moved := start - lowest.Pos[1]
+	if moved <= 0 || moved >= w.rowHeight()/2 {
+		t.Errorf("moved %v in the step's first tick, want a bit of the %v row", moved, w.rowHeight())
+	}
+	for i := 0; i < secondsToTicks(p.SlideTime); i++ {
+		w.Update()
+	}
+	if moved := start - lowest.Pos[1]; math.Abs(moved-w.rowHeight()) > 1e-9 {
+		t.Errorf("moved %v by the end of the slide, want a row, %v", moved, w.rowHeight())
+	}
+	//the new row slid in above the old ones, into the top row
+	for _, b := range w.Blocks {
+		if b.Pos[1]+b.Size[1] > w.StageSize[1]+1e-9 {
+			t.Errorf("block %d still above the stage at %v", b.ID, b.Pos)
+		}
+	}
+	if len(w.Blocks) == 0 || w.Blocks[len(w.Blocks)-1].ID != w.creep.nextID-1 {
+		t.Error("new row's blocks not numbered on from the old ones")
+	}
+}
+
+func TestPressureOverrun(t *testing.T) {
+	w := pressureWorld(Pressure{StepTime: 0.1, Speedup: 1, MinStepTime: 0.1, StartRows: 4})
+	top := w.Players[0].Paddle.Pos[1] + w.Players[0].Paddle.Size[1]
+	for ticks := 0; ; ticks++ {
+		if ticks > 100000 {
+			t.Fatal("the field never reached the paddle")
+		}
+		lowest := w.StageSize[1]
+		for _, b := range w.Blocks {
+			lowest = math.Min(lowest, b.Pos[1])
+		}
+		status := w.Update()
+		if status == WorldGameOver {
+			if lowest-w.rowHeight() > top+1e-9 {
+				t.Errorf("game over with the lowest block at %v, paddle top %v", lowest, top)
+			}
+			break
+		}
+		if lowest <= top {
+			t.Fatalf("still playing with a block at %v, paddle top %v", lowest, top)
+		}
+	}
+	if w.Players[0].Lives != StartingLives {
+		t.Errorf("overrun cost lives, %d left", w.Players[0].Lives)
+	}
+}
+
+func TestGeneratedRowsRepeat(t *testing.T) {
+	a, b := GeneratedRows{Seed: 5}, GeneratedRows{Seed: 5}
+	differ := false
+	for n := 0; n < 100; n++ {
+		row := a.Row(n)
+		if row != b.Row(n) || row != a.Row(n) {
+			t.Fatalf("row %d differs for the same seed", n)
+		}
+		if len(row) != GeneratedCols {
+			t.Errorf("row %d is %q, want %d cells", n, row, GeneratedCols)
+		}
+		level := Level{Rows: []string{row}}
+		if err := level.Validate(); err != nil {
+			t.Errorf("row %d: %v", n, err)
+		}
+		if row != (GeneratedRows{Seed: 6}).Row(n) {
+			differ = true
+		}
+	}
+	if !differ {
+		t.Error("seeds 5 and 6 give the same rows")
+	}
+}
+
+func TestLevelRows(t *testing.T) {
+	levels := LevelList{
+		{Rows: []string{"1..", ".2."}},
+		{Rows: []string{"..3"}},
+	}
+	//bottom to top, level after level, then round again
+	want := []string{".2.", "1..", "..3", ".2.", "1..", "..3"}
+	rows := LevelRows{levels}
+	for n, w := range want {
+		if got := rows.Row(n); got != w {
+			t.Errorf("row %d is %q, want %q", n, got, w)
+		}
+	}
+
+	//levels without rows are passed over, or given up on
+	for _, levels := range []LevelList{nil, {{}}, {{}, {Rows: []string{"999"}}}} {
+		got := LevelRows{levels}.Row(3)
+		if len(levels) == 2 {
+			if got != "999" {
+				t.Errorf("row 3 of a list with an empty level is %q", got)
+			}
+		} else if got != (GeneratedRows{}).Row(3) {
+			t.Errorf("row 3 of %d empty levels is %q, want a generated row", len(levels), got)
+		}
+	}
+}
+
+func TestLoadPressureValidates(t *testing.T) {
+	for _, bad := range []string{
+		`{"stepTime": 1, "minStepTime": 2}`,
+		`{"speedup": 1.5}`,
+		`{"slideTime": 5}`,
+		`{"startRows": -1}`,
+	} {
+		if _, err := LoadPressure(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s loaded without an error", bad)
+		}
+	}
+	p, err := LoadPressure(strings.NewReader(`{"stepTime": 4}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StepTime != 4 || p.Speedup != DefaultPressure.Speedup {
+		t.Errorf("loaded %+v, want the defaults with a step time of 4", p)
+	}
+}
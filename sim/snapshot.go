@@ -0,0 +1,82 @@
+package sim
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// Simulation state of a World, enough to rewind to it. Controllers are
+// shared with the live world rather than copied.
+type WorldSnapshot struct {
+	level   int
+	players []Player
+	paddles []Paddle
+	balls   []Ball
+	blocks  []Block
+	creep   creepState
+}
+
+func (w *World) Snapshot() *WorldSnapshot {
+	s := &WorldSnapshot{level: w.Level, creep: w.creep}
+	for _, p := range w.Players {
+		s.players = append(s.players, *p)
+		s.paddles = append(s.paddles, *p.Paddle)
+		s.balls = append(s.balls, *p.Ball)
+	}
+	s.blocks = make([]Block, len(w.Blocks))
+	for i, b := range w.Blocks {
+		s.blocks[i] = *b
+	}
+	return s
+}
+
+// Rewind to s. Events raised since it was taken are dropped, as far as the
+// world is concerned they never happened.
+func (w *World) Restore(s *WorldSnapshot) {
+	w.Level = s.level
+	w.creep = s.creep
+	for i, p := range w.Players {
+		paddle, ball := p.Paddle, p.Ball
+		controller := paddle.controller
+		*p = s.players[i]
+		*paddle = s.paddles[i]
+		paddle.controller = controller
+		*ball = s.balls[i]
+	}
+	w.Blocks = make([]*Block, len(s.blocks))
+	for i := range s.blocks {
+		b := s.blocks[i]
+		w.Blocks[i] = &b
+	}
+	w.events = nil
+}
+
+// Hash of everything that affects play, peers compare these to notice
+// their simulations have drifted apart
+func (s *WorldSnapshot) Checksum() uint32 {
+	h := fnv.New32a()
+	var buf [8]byte
+	put := func(values ...float64) {
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			h.Write(buf[:])
+		}
+	}
+	put(float64(s.level))
+	put(s.creep.wait, s.creep.fall, float64(s.creep.rows))
+	for i, p := range s.players {
+		serving := 0.0
+		if p.Serving {
+			serving = 1
+		}
+		put(float64(p.Score), float64(p.Lives), serving)
+		put(s.paddles[i].Pos[0], s.paddles[i].Pos[1])
+		b := s.balls[i]
+		put(b.Pos[0], b.Pos[1], b.Velocity[0], b.Velocity[1], float64(b.Owner))
+	}
+	for _, b := range s.blocks {
+		put(b.Pos[0], b.Pos[1], float64(b.HP))
+	}
+	return h.Sum32()
+}
@@ -0,0 +1,42 @@
+package sim
+
+import (
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+func Negate(v mgl.Vec2) mgl.Vec2 {
+	return mgl.Vec2{-v[0], -v[1]}
+}
+
+func Sign(a float64) float64 {
+	if a < 0 {
+		return -1
+	} else {
+		return 1
+	}
+}
+
+func MidPt(a, b mgl.Vec2) mgl.Vec2 {
+	return a.Add(b).Mul(0.5)
+}
+
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// v turned anticlockwise by angle radians
+func Rotate(v mgl.Vec2, angle float64) mgl.Vec2 {
+	sin, cos := math.Sincos(angle)
+	return mgl.Vec2{v[0]*cos - v[1]*sin, v[0]*sin + v[1]*cos}
+}
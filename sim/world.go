@@ -0,0 +1,406 @@
+// The game rules and physics, without any drawing or input devices, so
+// servers and tools can run games too.
+package sim
+
+import (
+	"fmt"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+	"time"
+)
+
+const (
+	// The simulation always steps by this much
+	TimePerUpdate = time.Duration(time.Second / 60.0)
+
+	StartingLives = 3
+	// Points awarded per destroyed block
+	BlockScore = 10
+	// A ball bouncing this long without touching a paddle or a breakable
+	// block is taken to be trapped and turned by TrappedTurn radians
+	TrappedTicks = 20 * 60
+	TrappedTurn  = 0.15
+)
+
+// The stage the game plays on: 2 high, and as wide as the 600x800 window
+// makes it
+var DefaultStageSize = mgl.Vec2{2 * 600.0 / 800.0, 2}
+
+type WorldStatus int
+
+const (
+	WorldRunning WorldStatus = iota
+	WorldLevelComplete
+	WorldGameOver
+)
+
+type WorldEventKind int
+
+const (
+	EventBlockDestroyed WorldEventKind = iota
+	EventPaddleHit
+	EventBallLost
+)
+
+// Something happened during Update that presentation might react to
+type WorldEvent struct {
+	Kind  WorldEventKind
+	Pos   mgl.Vec2
+	Color mgl.Vec4
+	//set for EventBlockDestroyed
+	Block *Block
+	//player it happened to, or who gets the credit
+	Player int
+}
+
+type GameMode int
+
+const (
+	ModeSingle GameMode = iota
+	//two players against the same blocks, sharing the score
+	ModeCoop
+	//two players racing for the higher score
+	ModeVersus
+)
+
+// Mode for a command line name: single, coop or versus
+func ParseGameMode(name string) (GameMode, error) {
+	switch name {
+	case "single":
+		return ModeSingle, nil
+	case "coop":
+		return ModeCoop, nil
+	case "versus":
+		return ModeVersus, nil
+	}
+	return 0, fmt.Errorf("unknown game mode %q, expected single, coop or versus", name)
+}
+
+func (m GameMode) Players() int {
+	if m == ModeSingle {
+		return 1
+	}
+	return 2
+}
+
+// One paddle and the ball it serves
+type Player struct {
+	Paddle *Paddle
+	Ball   *Ball
+	Score  int
+	Lives  int
+	//ball resting on the paddle, waiting for Launch
+	Serving bool
+	//ticks since the ball last hit a paddle or a block that can break
+	idle int
+}
+
+// Out of lives, the paddle stays but the ball is gone
+func (p *Player) Out() bool {
+	return p.Lives <= 0
+}
+
+// Everything that makes up one game in progress
+type World struct {
+	StageSize mgl.Vec2
+	Mode      GameMode
+	Players   []*Player
+	Blocks    []*Block
+	Level     int
+	Levels    LevelSource
+	//nil unless playing pressure, see SetPressure
+	Pressure *Pressure
+	creep    creepState
+	//since the last TakeEvents
+	events []WorldEvent
+}
+
+// Paddles start without controllers, see Paddle.SetController
+func MakeWorld(stageSize mgl.Vec2, mode GameMode) *World {
+	w := &World{
+		StageSize: stageSize,
+		Mode:      mode,
+		Level:     1,
+		Levels:    ClassicLevels{},
+	}
+	n := mode.Players()
+	for i := 0; i < n; i++ {
+		//spread round the cylinder, the first player in the middle
+		center := math.Mod(stageSize[0]/2+float64(i)*stageSize[0]/float64(n), stageSize[0])
+		paddle := MakePaddle(DefaultPaddleWidth, center, stageSize, nil)
+		ball := MakeBall(0.05, mgl.Vec2{center, stageSize[1] / 2})
+		ball.Owner = i
+		w.Players = append(w.Players, &Player{paddle, ball, 0, StartingLives, true, 0})
+	}
+	w.loadLevel()
+	return w
+}
+
+// Play levels from s instead, starting over the current one
+func (w *World) SetLevels(s LevelSource) {
+	w.Levels = s
+	w.loadLevel()
+}
+
+// Blocks and settings for the current level
+func (w *World) loadLevel() {
+	l := w.Levels.Level(w.Level)
+	w.Blocks = l.Blocks(w.StageSize)
+	for _, p := range w.Players {
+		p.Ball.Speed = l.BallStep()
+		//about the same centre
+		width := l.PaddleSpan()
+		p.Paddle.Nudge((p.Paddle.Size[0]-width)/2, w.StageSize)
+		p.Paddle.Size[0] = width
+	}
+}
+
+// Total over all players
+func (w *World) Score() int {
+	score := 0
+	for _, p := range w.Players {
+		score += p.Score
+	}
+	return score
+}
+
+// Index of the player with the highest score, -1 for a draw
+func (w *World) Winner() int {
+	winner := -1
+	best := -1
+	for i, p := range w.Players {
+		if p.Score > best {
+			winner, best = i, p.Score
+		} else if p.Score == best {
+			winner = -1
+		}
+	}
+	return winner
+}
+
+func (w *World) gameOver() bool {
+	if w.Pressure != nil && w.overrun() {
+		return true
+	}
+	out := 0
+	for _, p := range w.Players {
+		if p.Out() {
+			out++
+		}
+	}
+	if w.Mode == ModeVersus {
+		//one player out ends the race
+		return out > 0
+	}
+	return out == len(w.Players)
+}
+
+// Advance the simulation by one TimePerUpdate
+func (w *World) Update() WorldStatus {
+	for _, p := range w.Players {
+		p.Paddle.Update(w.StageSize)
+	}
+	w.separatePaddles()
+	for _, b := range w.Blocks {
+		b.Update(TimePerUpdate.Seconds(), w.StageSize)
+	}
+	if w.Pressure != nil {
+		w.creepDown(TimePerUpdate.Seconds())
+	}
+
+	// Collision handling
+	var colliders []Collider
+	// balls are dynamic, others are static
+	for i, p := range w.Players {
+		if p.Out() {
+			continue
+		}
+		if p.Serving {
+			p.Ball.Serve(p.Paddle)
+			continue
+		}
+		if p.Ball.Update(w.StageSize) {
+			w.emit(EventBallLost, p.Ball.Center(), mgl.Vec4{1, 1, 1, 1}, i)
+			p.Lives--
+			p.Serving = true
+			continue
+		}
+		p.idle++
+		if p.idle >= TrappedTicks {
+			//caught in a loop between solid blocks, knock it out of line
+			p.Ball.Velocity = Rotate(p.Ball.Velocity, TrappedTurn)
+			p.idle = 0
+		}
+		colliders = append(colliders, p.Ball)
+	}
+	if w.gameOver() {
+		return WorldGameOver
+	}
+
+	overhang := w.overhang()
+	for i, p := range w.Players {
+		paddles := w.withSeam(p.Paddle, overhang)
+		colliders = append(colliders, paddles...)
+		for _, q := range w.Players {
+			if q.Out() || q.Serving {
+				continue
+			}
+			for _, c := range paddles {
+				if hit, _, overlap := Collide(c, q.Ball); hit {
+					w.emit(EventPaddleHit, overlap.Center(), mgl.Vec4{1, 1, 1, 1}, i)
+					q.Ball.Owner = i
+					q.idle = 0
+					break
+				}
+			}
+		}
+	}
+	for _, b := range w.Blocks {
+		colliders = append(colliders, w.withSeam(b, overhang)...)
+	}
+
+	// blocks don't know which ball hit them, so credit by overlap first
+	hitBy := make(map[*Block]int)
+	for _, p := range w.Players {
+		if p.Out() || p.Serving {
+			continue
+		}
+		for _, b := range w.Blocks {
+			for _, c := range w.withSeam(b, overhang) {
+				if hit, _, _ := Collide(p.Ball, c); hit {
+					hitBy[b] = p.Ball.Owner
+					if !b.Solid {
+						p.idle = 0
+					}
+				}
+			}
+		}
+	}
+
+	CollideAll(colliders)
+
+	var killBlocks []int
+	for index, b := range w.Blocks {
+		if !b.Alive {
+			killBlocks = append(killBlocks, index)
+			player := hitBy[b]
+			w.Players[player].Score += BlockScore
+			w.events = append(w.events, WorldEvent{EventBlockDestroyed, b.Center(), b.Color, b, player})
+		}
+	}
+
+	for i := len(killBlocks) - 1; i >= 0; i-- {
+		idx := killBlocks[i]
+		w.Blocks = append(w.Blocks[:idx], w.Blocks[idx+1:]...)
+	}
+	for _, b := range w.Blocks {
+		if !b.Solid {
+			return WorldRunning
+		}
+	}
+	if w.Pressure != nil {
+		//cleared, so the next row comes straight away
+		w.creep.wait = 0
+		return WorldRunning
+	}
+	return WorldLevelComplete
+}
+
+// c, plus c seen from the other side of the seam if it reaches across it.
+// That's paddles and blocks hanging over the right edge, and anything at
+// the left edge within reach of a ball hanging over the right.
+func (w *World) withSeam(c Collider, overhang float64) []Collider {
+	colliders := []Collider{c}
+	pos, size := c.GetPos(), c.GetSize()
+	if pos[0]+size[0] > w.StageSize[0] {
+		colliders = append(colliders, &wrapped{c, -w.StageSize[0]})
+	}
+	if pos[0] < overhang {
+		colliders = append(colliders, &wrapped{c, w.StageSize[0]})
+	}
+	return colliders
+}
+
+// How far the balls in flight reach past the right edge of the stage
+func (w *World) overhang() float64 {
+	overhang := 0.0
+	for _, p := range w.Players {
+		if p.Out() || p.Serving {
+			continue
+		}
+		right := p.Ball.GetPos()[0] + p.Ball.GetSize()[0]
+		overhang = math.Max(overhang, right-w.StageSize[0])
+	}
+	return overhang
+}
+
+// A collider as seen from the other side of the seam, shifted by a stage
+// width. Hits are passed on to the collider itself.
+type wrapped struct {
+	Collider
+	shift float64
+}
+
+func (w *wrapped) GetPos() mgl.Vec2 {
+	pos := w.Collider.GetPos()
+	return mgl.Vec2{pos[0] + w.shift, pos[1]}
+}
+
+// Shortest signed distance from a to b round the cylinder
+func (w *World) WrapDelta(a, b float64) float64 {
+	width := w.StageSize[0]
+	d := math.Mod(b-a, width)
+	if d > width/2 {
+		d -= width
+	} else if d < -width/2 {
+		d += width
+	}
+	return d
+}
+
+// Paddles share the bottom row, so they push each other apart rather than
+// passing through. The stage wraps, so this can't use Collide.
+func (w *World) separatePaddles() {
+	for i := 0; i < len(w.Players); i++ {
+		for j := i + 1; j < len(w.Players); j++ {
+			a, b := w.Players[i].Paddle, w.Players[j].Paddle
+			d := w.WrapDelta(a.Center()[0], b.Center()[0])
+			overlap := (a.Size[0]+b.Size[0])/2 - math.Abs(d)
+			if overlap <= 0 {
+				continue
+			}
+			push := overlap / 2 * Sign(d)
+			if d == 0 {
+				push = overlap / 2
+			}
+			a.Nudge(-push, w.StageSize)
+			b.Nudge(push, w.StageSize)
+		}
+	}
+}
+
+func (w *World) emit(kind WorldEventKind, pos mgl.Vec2, color mgl.Vec4, player int) {
+	w.events = append(w.events, WorldEvent{kind, pos, color, nil, player})
+}
+
+// Events since the last call, oldest first
+func (w *World) TakeEvents() []WorldEvent {
+	events := w.events
+	w.events = nil
+	return events
+}
+
+func (w *World) NextLevel() {
+	w.Level++
+	w.loadLevel()
+	for _, p := range w.Players {
+		p.Serving = !p.Out()
+	}
+}
+
+// Send a player's ball off their paddle, if it's waiting there
+func (w *World) Launch(player int) {
+	if player < len(w.Players) {
+		w.Players[player].Serving = false
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	"time"
+)
+
+// A screen of the game: title menu, playing, pause overlay and so on.
+// Only the state on top of the stack gets input and simulation ticks.
+type GameState interface {
+	// return true if the key was consumed
+	HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool
+	// fixed time-step tick, see TimePerUpdate
+	Update()
+	// once per rendered frame
+	Draw(elapsed time.Duration)
+	// overlays are drawn on top of the state below them instead of replacing it
+	IsOverlay() bool
+}
+
+type StateStack struct {
+	states []GameState
+}
+
+var gStates StateStack
+
+func (s *StateStack) Top() GameState {
+	if len(s.states) == 0 {
+		return nil
+	}
+	return s.states[len(s.states)-1]
+}
+
+func (s *StateStack) Push(state GameState) {
+	s.states = append(s.states, state)
+}
+
+func (s *StateStack) Pop() {
+	if len(s.states) > 0 {
+		s.states = s.states[:len(s.states)-1]
+	}
+}
+
+// Swap the top state for another
+func (s *StateStack) Replace(state GameState) {
+	s.Pop()
+	s.Push(state)
+}
+
+// Drop every state and start over from state
+func (s *StateStack) Reset(state GameState) {
+	s.states = nil
+	s.Push(state)
+}
+
+func (s *StateStack) HandleKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	if top := s.Top(); top != nil {
+		return top.HandleKey(key, scancode, action, mods)
+	}
+	return false
+}
+
+func (s *StateStack) Update() {
+	if top := s.Top(); top != nil {
+		top.Update()
+	}
+}
+
+// Draw from the topmost opaque state upwards, so overlays show the frozen
+// states underneath them
+func (s *StateStack) Draw(elapsed time.Duration) {
+	first := len(s.states) - 1
+	for first > 0 && s.states[first].IsOverlay() {
+		first--
+	}
+	for i := first; i >= 0 && i < len(s.states); i++ {
+		s.states[i].Draw(elapsed)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	mgl32 "github.com/go-gl/mathgl/mgl32"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"image"
+	"image/color"
+	"sort"
+	"unicode"
+)
+
+// Atlas cells leave a blank pixel on the right and bottom of every glyph so
+// neighbouring glyphs never bleed into each other
+const (
+	GlyphCellWidth   = FontGlyphWidth + 1
+	GlyphCellHeight  = FontGlyphHeight + 1
+	FontAtlasColumns = 16
+)
+
+type Font struct {
+	tex     uint32
+	atlasW  int
+	atlasH  int
+	glyphs  map[rune]int //rune -> atlas cell
+	missing int          //cell drawn for runes the font doesn't know
+}
+
+// Rasterise the built-in bitmap font into a single texture
+func MakeBitmapFont() (*Font, error) {
+	var runes []rune
+	for r := range fontGlyphs {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	rows := (len(runes) + FontAtlasColumns - 1) / FontAtlasColumns
+	atlasW := FontAtlasColumns * GlyphCellWidth
+	atlasH := rows * GlyphCellHeight
+	img := image.NewRGBA(image.Rect(0, 0, atlasW, atlasH))
+
+	glyphs := make(map[rune]int)
+	for cell, r := range runes {
+		glyphs[r] = cell
+		x0 := (cell % FontAtlasColumns) * GlyphCellWidth
+		y0 := (cell / FontAtlasColumns) * GlyphCellHeight
+		for y, line := range fontGlyphs[r] {
+			for x, c := range line {
+				if c != ' ' {
+					img.Set(x0+x, y0+y, color.White)
+				}
+			}
+		}
+	}
+
+	tex, err := createTextureFromImage(img, gl.NEAREST)
+	if err != nil {
+		return nil, fmt.Errorf("font: %w", err)
+	}
+	return &Font{tex, atlasW, atlasH, glyphs, glyphs['?']}, nil
+}
+
+func (f *Font) cell(r rune) int {
+	if cell, ok := f.glyphs[unicode.ToUpper(r)]; ok {
+		return cell
+	}
+	return f.missing
+}
+
+// Draws strings in screen space. Geometry is rebuilt on every Draw, which is
+// fine for the handful of short labels the HUD needs.
+type TextRenderer struct {
+	font *Font
+	comp RenderComponent
+	//screen units per font pixel
+	scale float64
+}
+
+func MakeTextRenderer(font *Font, scale float64) (*TextRenderer, error) {
+	program, err := GetHUDShaderProgram()
+	if err != nil {
+		return nil, err
+	}
+	vao, vbo, indexBuffer := makeDynamicVertexArrayObject()
+	comp := MakeRenderComponent(vao, vbo, indexBuffer, 0, font.tex, program)
+	return &TextRenderer{font, comp, scale}, nil
+}
+
+func (t *TextRenderer) LineHeight() float64 {
+	return GlyphCellHeight * t.scale
+}
+
+// Size of s in screen units, lines are separated by '\n'
+func (t *TextRenderer) Measure(s string) mgl.Vec2 {
+	lines := 1
+	longest := 0
+	length := 0
+	for _, r := range s {
+		if r == '\n' {
+			lines++
+			length = 0
+			continue
+		}
+		length++
+		if length > longest {
+			longest = length
+		}
+	}
+	return mgl.Vec2{float64(longest*GlyphCellWidth) * t.scale, float64(lines) * t.LineHeight()}
+}
+
+// One quad per glyph, origin at the top left of the first line and y
+// pointing down the screen
+func (t *TextRenderer) Vertexify(s string) (vertices []float32, indices []uint16) {
+	w := float32(FontGlyphWidth * t.scale)
+	h := float32(FontGlyphHeight * t.scale)
+	var x, y float32
+	for _, r := range s {
+		if r == '\n' {
+			x = 0
+			y += float32(t.LineHeight())
+			continue
+		}
+		cell := t.font.cell(r)
+		u0 := float32((cell%FontAtlasColumns)*GlyphCellWidth) / float32(t.font.atlasW)
+		v0 := float32((cell/FontAtlasColumns)*GlyphCellHeight) / float32(t.font.atlasH)
+		u1 := u0 + float32(FontGlyphWidth)/float32(t.font.atlasW)
+		v1 := v0 + float32(FontGlyphHeight)/float32(t.font.atlasH)
+
+		base := uint16(len(vertices) / VertexFloats)
+		vertices = append(vertices,
+			x, y, 0, u0, v0, 0, 0, 1,
+			x+w, y, 0, u1, v0, 0, 0, 1,
+			x, y+h, 0, u0, v1, 0, 0, 1,
+			x+w, y+h, 0, u1, v1, 0, 0, 1,
+		)
+		indices = append(indices,
+			base, base+2, base+1,
+			base+1, base+2, base+3,
+		)
+		x += float32(GlyphCellWidth * t.scale)
+	}
+	return
+}
+
+// P is the screen-space projection, see HUD.Projection
+func (t *TextRenderer) Draw(s string, pos mgl.Vec2, tint mgl.Vec4, P mgl32.Mat4) {
+	vertices, indices := t.Vertexify(s)
+	if len(indices) == 0 {
+		return
+	}
+	updateVertexArrayObject(t.comp.vao, t.comp.vbo, t.comp.indexBuffer, vertices, indices)
+	t.comp.numIndices = int32(len(indices))
+
+	t.comp.SetTint(tint)
+	t.comp.Draw(pos, P)
+}
@@ -4,6 +4,7 @@
 
 attribute vec3 position;
 attribute vec2 texCoord;
+attribute vec3 normal;
 
 uniform vec2 offset;
 uniform mat4 VP;
@@ -16,9 +17,13 @@ uniform float cylinderHeight;
 uniform float levelWidth;
 //Level height, mapped to cylinder height
 uniform float levelHeight;
+//Texture sub-rectangle, xy offset and zw scale
+uniform vec4 texRegion;
 
 varying vec2 TexCoordOut;
 varying float normPosOut;
+varying vec3 NormalOut;
+varying vec3 WorldPosOut;
 
 void main()
 {
@@ -30,11 +35,26 @@ void main()
    float angleNorm = levelPosition.x / levelWidth;
    float angleRad = angleNorm * twopi;
 
-   float xOut = cylinderRadius * sin(angleRad);
+   //z lifts the vertex off the cylinder surface, scaled like x so meshes
+   //keep their proportions along the stage
+   float radius = cylinderRadius + levelPosition.z * twopi * cylinderRadius / levelWidth;
+
+   float xOut = radius * sin(angleRad);
    float yOut = levelPosition.y * levelHeight / cylinderHeight;
-   float zOut = cylinderRadius * cos(angleRad);
+   float zOut = radius * cos(angleRad);
 
    gl_Position = VP * vec4(xOut, yOut, zOut, 1.0);
-   TexCoordOut = texCoord;
+
+   //Stage normals are x along the stage, y up and z out of the cylinder.
+   //Undo the stage to world scaling (z scales like x), then rotate the
+   //tangent and radial axes to this point on the cylinder.
+   float xScale = twopi * cylinderRadius / levelWidth;
+   float yScale = levelHeight / cylinderHeight;
+   vec3 n = vec3(normal.x / xScale, normal.y / yScale, normal.z / xScale);
+   vec3 tangent = vec3(cos(angleRad), 0.0, -sin(angleRad));
+   vec3 radial = vec3(sin(angleRad), 0.0, cos(angleRad));
+   NormalOut = normalize(n.x * tangent + vec3(0.0, n.y, 0.0) + n.z * radial);
+   WorldPosOut = vec3(xOut, yOut, zOut);
+   TexCoordOut = texRegion.xy + texCoord * texRegion.zw;
    normPosOut = angleNorm;
 }
\ No newline at end of file
@@ -0,0 +1,36 @@
+#version 330
+
+#define M_PI 3.1415926535897932384626433832795
+
+in vec3 position;
+in vec2 texCoord;
+in vec4 color;
+
+uniform mat4 VP;
+
+//Radius of output cylinder
+uniform float cylinderRadius;
+//Height of output cylinder
+uniform float cylinderHeight;
+//Level width, mapped to cylinder circumference
+uniform float levelWidth;
+//Level height, mapped to cylinder height
+uniform float levelHeight;
+
+out vec2 fragTexCoord;
+out vec4 fragColor;
+
+void main()
+{
+   //particles are already in level coordinates, no offset
+   float twopi = 2 * M_PI;
+   float angleRad = position.x / levelWidth * twopi;
+
+   float xOut = cylinderRadius * sin(angleRad);
+   float yOut = position.y * levelHeight / cylinderHeight;
+   float zOut = cylinderRadius * cos(angleRad);
+
+   gl_Position = VP * vec4(xOut, yOut, zOut, 1.0);
+   fragTexCoord = texCoord;
+   fragColor = color;
+}
@@ -0,0 +1,12 @@
+#version 120
+
+//Full-screen quad in clip space
+attribute vec2 position;
+
+varying vec2 TexCoordOut;
+
+void main()
+{
+  TexCoordOut = position * 0.5 + 0.5;
+  gl_Position = vec4(position, 0.0, 1.0);
+}
\ No newline at end of file
@@ -0,0 +1,11 @@
+#version 330
+
+//Full-screen quad in clip space
+in vec2 position;
+
+out vec2 fragTexCoord;
+
+void main() {
+   fragTexCoord = position * 0.5 + 0.5;
+   gl_Position = vec4(position, 0.0, 1.0);
+}
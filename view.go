@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"github.com/CandleEnds/go-breakout/sim"
+	mgl32 "github.com/go-gl/mathgl/mgl32"
+	mgl "github.com/go-gl/mathgl/mgl64"
+	"math"
+)
+
+const (
+	// Brick mesh shape, in stage units
+	BlockDepth = 0.04
+	BlockBevel = 0.012
+	// Mesh subdivisions along the width, so bricks bend with the cylinder
+	BlockSegments = 6
+
+	// Seconds a destroyed block takes to fade out
+	BlockFadeTime = 0.3
+)
+
+// Paddle tint per player, the first is untinted
+var PlayerColors = []mgl.Vec4{
+	{1, 1, 1, 1},
+	{1, 0.6, 0.3, 1},
+}
+
+type meshKey struct {
+	kind string
+	size mgl.Vec2
+}
+
+// Draws a sim.World. Everything of the same kind and size shares one mesh,
+// tinted per draw.
+type WorldView struct {
+	meshes map[meshKey]*RenderComponent
+	//camera framing, eased towards the players each tick
+	cameraAngle float64
+	cameraZoom  float64
+	//width over height of the viewport drawn into, 0 for the whole screen
+	aspect float64
+}
+
+func MakeWorldView() *WorldView {
+	return &WorldView{meshes: make(map[meshKey]*RenderComponent)}
+}
+
+// Mesh for a kind of object, made the first time it's needed. nil if it
+// couldn't be made, which is only reported once.
+func (v *WorldView) mesh(kind string, size mgl.Vec2) *RenderComponent {
+	key := meshKey{kind, size}
+	if r, ok := v.meshes[key]; ok {
+		return r
+	}
+	var mesh Mesh
+	switch kind {
+	case "block":
+		mesh = VertexifyBrick(size, BlockDepth, BlockBevel, BlockSegments)
+	case "paddle":
+		mesh = VertexifyBar(size, 8, 16)
+	case "ball":
+		mesh = VertexifySphere(size[0]/2, 12, 16)
+	}
+	r, err := MakeRenderMesh(mesh, kind)
+	if err != nil {
+		fmt.Printf("can't draw %v: %v\n", kind, err)
+		r = nil
+	}
+	v.meshes[key] = r
+	return r
+}
+
+func (v *WorldView) draw(kind string, pos, size mgl.Vec2, tint mgl.Vec4, VP mgl32.Mat4) {
+	if r := v.mesh(kind, size); r != nil {
+		r.SetTint(tint)
+		r.Draw(pos, VP)
+	}
+}
+
+// Turn to face the point between the paddles and pull back as they
+// separate, until the camera looks down the cylinder at both. Call once
+// per tick.
+func (v *WorldView) Update(w *sim.World) {
+	if len(w.Players) == 1 {
+		return
+	}
+	v.Face(w, v.focus(w))
+	a := w.Players[0].Paddle.Center()[0]
+	b := w.Players[1].Paddle.Center()[0]
+	zoom := math.Abs(w.WrapDelta(a, b)) / (w.StageSize[0] / 2)
+	v.cameraZoom += (zoom - v.cameraZoom) * CameraEase
+}
+
+// Share of the way the camera moves to its new framing each tick
+const CameraEase = 0.05
+
+// Turn the camera towards stage x, easing there over the next ticks
+func (v *WorldView) Face(w *sim.World, x float64) {
+	start := w.StageSize[0] / 2
+	angle := w.WrapDelta(start, x) / w.StageSize[0] * 2 * math.Pi
+	//ease along the shorter arc so wrapping past 2pi doesn't spin round
+	d := math.Mod(angle-v.cameraAngle, 2*math.Pi)
+	if d > math.Pi {
+		d -= 2 * math.Pi
+	} else if d < -math.Pi {
+		d += 2 * math.Pi
+	}
+	v.cameraAngle += d * CameraEase
+}
+
+// Stage x halfway between the paddles, going the short way round
+func (v *WorldView) focus(w *sim.World) float64 {
+	first := w.Players[0].Paddle.Center()[0]
+	if len(w.Players) == 1 {
+		return first
+	}
+	second := w.Players[1].Paddle.Center()[0]
+	return first + w.WrapDelta(first, second)/2
+}
+
+// Eye and target for the current framing. The single player view only
+// turns when told to Face somewhere.
+func (v *WorldView) camera(w *sim.World) (eye, target mgl.Vec3) {
+	eye, target = gCamPos, mgl.Vec3{0, 3, 0}
+	if len(w.Players) == 1 {
+		return mgl.Rotate3DY(v.cameraAngle).Mul3x1(eye), target
+	}
+	t := v.cameraZoom
+	overhead := mgl.Vec3{0, 20, 4}
+	eye = eye.Mul(1 - t).Add(overhead.Mul(t))
+	eye = mgl.Rotate3DY(v.cameraAngle).Mul3x1(eye)
+	target = target.Mul(1 - t).Add(mgl.Vec3{0, 2, 0}.Mul(t))
+	return eye, target
+}
+
+// Draws the world, returning the view-projection used so callers can draw
+// more in the same space
+func (v *WorldView) Draw(w *sim.World) mgl32.Mat4 {
+	persp := gScreen.Projection()
+	if v.aspect != 0 {
+		persp = gScreen.ProjectionAt(v.aspect)
+	}
+	model := mgl32.Ident4()
+	eye, target := v.camera(w)
+	view := mgl32.LookAt(
+		float32(eye[0]), float32(eye[1]), float32(eye[2]),
+		float32(target[0]), float32(target[1]), float32(target[2]),
+		0, 1, 0)
+	MVP := persp.Mul4(view.Mul4(model))
+
+	gLighting.eye = eye
+	var lights []PointLight
+	for _, p := range w.Players {
+		if p.Out() {
+			continue
+		}
+		ball := p.Ball.Center()
+		lights = append(lights, PointLight{StageToWorld(mgl.Vec3{ball[0], ball[1], p.Ball.Size[1]}), BallLightColor, BallLightRadius})
+	}
+	gLighting.SetPointLights(lights)
+
+	for _, b := range w.Blocks {
+		v.draw("block", b.Pos, b.Size, b.Tint(), MVP)
+	}
+
+	for i, p := range w.Players {
+		v.draw("paddle", p.Paddle.Pos, p.Paddle.Size, PlayerColors[i%len(PlayerColors)], MVP)
+		if !p.Out() {
+			v.draw("ball", p.Ball.Pos, p.Ball.Size, mgl.Vec4{1, 1, 1, 1}, MVP)
+		}
+	}
+	return MVP
+}
+
+// Draw a destroyed block partway through fading out, t from 0 to 1
+func (v *WorldView) DrawFading(b *sim.Block, VP mgl32.Mat4, t float64) {
+	tint := b.Color
+	tint[3] *= 1 - t
+	v.draw("block", b.Pos, b.Size, tint, VP)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	glfw "github.com/go-gl/glfw3/v3.2/glfw"
+	mgl32 "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"strings"
+)
+
+// Vertical field of view handed to mgl32.Perspective at the design aspect
+const CameraFovY = 45
+
+// Screen tracks the window and its framebuffer. The two differ on HiDPI
+// displays, where one window unit covers several framebuffer pixels.
+type Screen struct {
+	windowWidth  int
+	windowHeight int
+	fbWidth      int
+	fbHeight     int
+
+	// windowed placement, restored when leaving fullscreen
+	savedX      int
+	savedY      int
+	savedWidth  int
+	savedHeight int
+}
+
+var gScreen Screen
+
+// A GL context version to ask for, and the shader variants that run on it
+type GLProfile struct {
+	name  string
+	major int
+	minor int
+	core  bool
+	//appended to shader names, see shaderFile
+	shaderSuffix string
+}
+
+// Tried in order until a context is created. 2.1 is what software
+// renderers like Mesa's llvmpipe reliably offer. It draws without vertex
+// array objects, and has post-processing only if the driver has
+// framebuffer objects, see loadGL.
+var GLProfiles = []GLProfile{
+	{"4.1", 4, 1, true, "_330"},
+	{"3.3", 3, 3, true, "_330"},
+	{"2.1", 2, 1, false, ""},
+}
+
+var gGLProfile = GLProfiles[0]
+
+var gGLVersion = flag.String("gl", "", "only try this GL version: 4.1, 3.3 or 2.1")
+
+// Open the window with the best GL context the driver gives us, setting
+// gGLProfile to match
+func CreateWindow(width, height int, title string) (*glfw.Window, error) {
+	var failures []string
+	for _, profile := range GLProfiles {
+		if *gGLVersion != "" && *gGLVersion != profile.name {
+			continue
+		}
+		glfw.DefaultWindowHints()
+		glfw.WindowHint(glfw.ContextVersionMajor, profile.major)
+		glfw.WindowHint(glfw.ContextVersionMinor, profile.minor)
+		glfw.WindowHint(glfw.Resizable, glfw.True)
+		if profile.core {
+			glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+			glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+		}
+		if glDebugBuild {
+			glfw.WindowHint(glfw.OpenGLDebugContext, glfw.True)
+		}
+
+		window, err := glfw.CreateWindow(width, height, title, nil, nil)
+		if err == nil {
+			gGLProfile = profile
+			return window, nil
+		}
+		failures = append(failures, fmt.Sprintf("GL %s: %v", profile.name, err))
+	}
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("unknown GL version %q", *gGLVersion)
+	}
+	return nil, fmt.Errorf("no usable GL context\n%s", strings.Join(failures, "\n"))
+}
+
+func InitScreen(w *glfw.Window) {
+	gScreen.windowWidth, gScreen.windowHeight = w.GetSize()
+	gScreen.fbWidth, gScreen.fbHeight = w.GetFramebufferSize()
+	SetViewport(gScreen.fbWidth, gScreen.fbHeight)
+
+	w.SetSizeCallback(glfwWindowSizeCallback)
+	w.SetFramebufferSizeCallback(glfwFramebufferSizeCallback)
+}
+
+func glfwWindowSizeCallback(w *glfw.Window, width, height int) {
+	gScreen.windowWidth = width
+	gScreen.windowHeight = height
+}
+
+func glfwFramebufferSizeCallback(w *glfw.Window, width, height int) {
+	// minimised windows report 0x0, keep the last good size around
+	if width == 0 || height == 0 {
+		return
+	}
+	gScreen.fbWidth = width
+	gScreen.fbHeight = height
+	SetViewport(width, height)
+}
+
+// Aspect ratio of the framebuffer, falls back to the design size
+func (s *Screen) Aspect() float64 {
+	if s.fbWidth == 0 || s.fbHeight == 0 {
+		return float64(WindowWidth) / float64(WindowHeight)
+	}
+	return float64(s.fbWidth) / float64(s.fbHeight)
+}
+
+// Framebuffer pixels per window unit, 1 on normal displays
+func (s *Screen) Scale() float64 {
+	if s.windowWidth == 0 {
+		return 1
+	}
+	return float64(s.fbWidth) / float64(s.windowWidth)
+}
+
+// Perspective projection for the current framebuffer. Windows narrower than
+// the design aspect widen the vertical fov so the stage never gets cropped
+// at the sides, wider windows just show more background.
+func (s *Screen) Projection() mgl32.Mat4 {
+	return s.ProjectionAt(s.Aspect())
+}
+
+// Projection for a viewport of the given aspect, e.g. part of the screen
+func (s *Screen) ProjectionAt(aspect float64) mgl32.Mat4 {
+	designAspect := float64(WindowWidth) / float64(WindowHeight)
+	fovy := float64(CameraFovY)
+	if aspect < designAspect {
+		fovy = 2 * math.Atan(math.Tan(fovy/2)*designAspect/aspect)
+	}
+	return mgl32.Perspective(float32(fovy), float32(aspect), 0.1, 100)
+}
+
+func IsFullscreen(w *glfw.Window) bool {
+	return w.GetMonitor() != nil
+}
+
+// Switch between windowed mode and fullscreen on the primary monitor
+func ToggleFullscreen(w *glfw.Window) {
+	if IsFullscreen(w) {
+		w.SetMonitor(nil, gScreen.savedX, gScreen.savedY,
+			gScreen.savedWidth, gScreen.savedHeight, 0)
+		return
+	}
+
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		return
+	}
+	gScreen.savedX, gScreen.savedY = w.GetPos()
+	gScreen.savedWidth, gScreen.savedHeight = w.GetSize()
+	mode := monitor.GetVideoMode()
+	w.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+}